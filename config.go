@@ -1,7 +1,6 @@
 package hypersql
 
 import (
-	"context"
 	"crypto/tls"
 	"errors"
 	"time"
@@ -23,34 +22,57 @@ var (
 )
 
 type Config struct {
-	Transport     string         `json:"transport" yaml:"transport" toml:"transport"`
-	Dialect       string         `json:"dialect" yaml:"dialect"  toml:"dialect"`
-	Host          string         `json:"host" yaml:"host" toml:"host"`
-	Port          int            `json:"port" yaml:"port" toml:"port"`
-	Name          string         `json:"name" yaml:"name" toml:"name"`
-	User          string         `json:"user" yaml:"user" toml:"user"`
-	Password      string         `json:"password" yaml:"password" toml:"password"`
-	Params        ConfigParams   `json:"params" yaml:"params" toml:"params"`
-	DialTimeout   time.Duration  `json:"dial_timeout" yaml:"dial_timeout" toml:"dial_timeout"`
-	ConnInitSQL   string         `json:"conn_init_sql" yaml:"conn_init_sql" toml:"conn_init_sql"`
-	ValidationSQL string         `json:"validation_sql" yaml:"validation_sql" toml:"validation_sql"`
-	Loc           *time.Location `json:"loc" yaml:"loc" toml:"loc"`
-	Logger        Logger         `json:"-" yaml:"-" toml:"-"`
+	Transport string `json:"transport" yaml:"transport" toml:"transport"`
+	Dialect   string `json:"dialect" yaml:"dialect"  toml:"dialect"`
+	Host      string `json:"host" yaml:"host" toml:"host"`
+	Port      int    `json:"port" yaml:"port" toml:"port"`
+	Name      string `json:"name" yaml:"name" toml:"name"`
+	User      string `json:"user" yaml:"user" toml:"user"`
+	Password  string `json:"password" yaml:"password" toml:"password"`
+	// CredentialProvider overrides User and Password when each new connection is made, see CredentialProvider.
+	CredentialProvider CredentialProvider `json:"-" yaml:"-" toml:"-"`
+	Params             ConfigParams       `json:"params" yaml:"params" toml:"params"`
+	DialTimeout        time.Duration      `json:"dial_timeout" yaml:"dial_timeout" toml:"dial_timeout"`
+	ConnInitSQL        string             `json:"conn_init_sql" yaml:"conn_init_sql" toml:"conn_init_sql"`
+	ValidationSQL      string             `json:"validation_sql" yaml:"validation_sql" toml:"validation_sql"`
+	Loc                *time.Location     `json:"loc" yaml:"loc" toml:"loc"`
+	Logger             Logger             `json:"-" yaml:"-" toml:"-"`
+
+	// Hosts are tried in order when connecting, Host and Port are ignored if it is set.
+	Hosts []HostPort `json:"hosts" yaml:"hosts" toml:"hosts"`
+	// ShuffleHosts tries Hosts in random order.
+	ShuffleHosts bool `json:"shuffle_hosts" yaml:"shuffle_hosts" toml:"shuffle_hosts"`
 
 	TLSCert   *TLSCert    `json:"tls_cert" yaml:"tls_cert" toml:"tls_cert"`
 	TLSConfig *tls.Config `json:"-" yaml:"-" toml:"-"`
 
 	// Driver related
-	DriverHooks    DriverHooks
-	DriverWrappers DriverWrappers
+	DriverHooks    DriverHooks    `json:"-" yaml:"-" toml:"-"`
+	DriverWrappers DriverWrappers `json:"-" yaml:"-" toml:"-"`
 
-	AfterHandlers AfterHandlers
+	AfterHandlers AfterHandlers `json:"-" yaml:"-" toml:"-"`
+
+	CloseHandlers CloseHandlers `json:"-" yaml:"-" toml:"-"`
 
 	// Connection parameters
 	ConnMaxLifetime time.Duration `json:"conn_max_lifetime" yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
 	ConnMaxIdleTime time.Duration `json:"conn_max_idle_time" yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
 	MaxOpenConns    int           `json:"max_open_conns" yaml:"max_open_conns" toml:"max_open_conns"`
 	MaxIdleConns    int           `json:"max_idle_conns" yaml:"max_idle_conns" toml:"max_idle_conns"`
+	// MinIdleConns is the number of idle connections that DB keeps, they are opened at startup too.
+	// It must not exceed MaxIdleConns, and the refills only use the free slots of the pool.
+	MinIdleConns int `json:"min_idle_conns" yaml:"min_idle_conns" toml:"min_idle_conns"`
+	// WarmUpConns is the number of connections opened at startup.
+	WarmUpConns int `json:"warm_up_conns" yaml:"warm_up_conns" toml:"warm_up_conns"`
+	// ExpectedReplicas is the number of application instances sharing the server,
+	// the default MaxOpenConns is the max connections of server divided by it.
+	ExpectedReplicas int `json:"expected_replicas" yaml:"expected_replicas" toml:"expected_replicas"`
+
+	// HealthCheckTimeout bounds the health check of DB, DefaultHealthCheckTimeout is used if not set.
+	HealthCheckTimeout time.Duration `json:"health_check_timeout" yaml:"health_check_timeout" toml:"health_check_timeout"`
+
+	// HealthThresholds is used by health report of DB, DefaultHealthThresholds is used if not set.
+	HealthThresholds *HealthThresholds `json:"health_thresholds" yaml:"health_thresholds" toml:"health_thresholds"`
 
 	// Enable debug
 	Debug bool
@@ -59,6 +81,15 @@ type Config struct {
 	Extra any
 }
 
+type HostPort struct {
+	Host string `json:"host" yaml:"host" toml:"host"`
+	Port int    `json:"port" yaml:"port" toml:"port"`
+}
+
+func (h HostPort) String() string {
+	return hostPortToAddr(h.Host, h.Port)
+}
+
 func SetupConfig(c *Config) *Config {
 	if c == nil {
 		c = new(Config)
@@ -83,16 +114,20 @@ func (c *Config) SetDefaults() {
 	}
 }
 
-func (c *Config) Validate(ctx context.Context) error {
-	if c == nil {
-		return ErrNilConfig
+// HostPorts returns Hosts if it is set, otherwise Host and Port.
+// The hosts without port use Port instead.
+func (c *Config) HostPorts() []HostPort {
+	if len(c.Hosts) == 0 {
+		return []HostPort{{Host: c.Host, Port: c.Port}}
 	}
-	vv, ok := c.Extra.(Validator)
-	if ok {
-		return vv.Validate(ctx)
-	} else {
-		return nil
+	hosts := make([]HostPort, len(c.Hosts))
+	for i, h := range c.Hosts {
+		if h.Port == 0 {
+			h.Port = c.Port
+		}
+		hosts[i] = h
 	}
+	return hosts
 }
 
 func (c *Config) DBInfo() DBInfo {
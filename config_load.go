@@ -0,0 +1,533 @@
+package hypersql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ConfigFormatYAML = "yaml"
+	ConfigFormatJSON = "json"
+	ConfigFormatTOML = "toml"
+
+	// EnvConfigPrefix is the prefix of environment variables of LoadConfigFromEnv,
+	// e.g. HYPERSQL_MAX_OPEN_CONNS for max_open_conns.
+	EnvConfigPrefix = "HYPERSQL_"
+
+	// EnvDatabaseURL is the environment variable of database URL, see ParseURL.
+	EnvDatabaseURL = "DATABASE_URL"
+)
+
+var (
+	ErrUnsupportedConfigFormat = errors.New("unsupported config format")
+
+	// extraTypes holds the Config.Extra type of each dialect, which is decoded by LoadConfig.
+	extraTypes = make(map[string]reflect.Type)
+
+	configType   = reflect.TypeFor[Config]()
+	durationType = reflect.TypeFor[time.Duration]()
+	locationType = reflect.TypeFor[*time.Location]()
+	hostPortType = reflect.TypeFor[HostPort]()
+)
+
+// ConfigError is the failure of decoding a config field, Path is like hosts[1].port.
+type ConfigError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid config field %s: %v", e.Path, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// RegisterExtraType registers the type of Config.Extra of the dialect, e.g. PostgresExtra.
+func RegisterExtraType[T any](dialect string) {
+	extraTypes[dialect] = reflect.TypeFor[T]()
+}
+
+// LoadConfig loads the config from a YAML, JSON or TOML file, the format is detected by the file extension.
+func LoadConfig(path string) (*Config, error) {
+	format, err := configFormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseConfig(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load config file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// ParseConfig parses the config of the format.
+// Durations are strings like "30s" or integers of nanoseconds, Loc is an IANA time zone name,
+// and Extra is decoded to the type registered for dialect.
+func ParseConfig(data []byte, format string) (*Config, error) {
+	raw, err := unmarshalConfig(data, format)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Config)
+	if err := decodeConfig("", raw, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadConfigFromEnv loads the config from DATABASE_URL and HYPERSQL_* environment variables,
+// the latter override the former. The variables are the upper cased field paths, e.g.
+// HYPERSQL_TLS_CERT_CA_FILE for tls_cert.ca_file, HYPERSQL_PARAMS_SSLMODE for params.sslmode,
+// and HYPERSQL_EXTRA_STATEMENT_CACHE_CAPACITY for extra.statement_cache_capacity.
+// Hosts are comma separated, e.g. HYPERSQL_HOSTS=h1:5432,h2:5432.
+func LoadConfigFromEnv() (*Config, error) {
+	return loadConfigFromEnv(os.Environ())
+}
+
+func loadConfigFromEnv(environ []string) (*Config, error) {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+
+	c := new(Config)
+	if u := env[EnvDatabaseURL]; len(u) > 0 {
+		pc, err := ParseURL(u)
+		if err != nil {
+			// The URL may contain the password.
+			var ue *url.Error
+			if errors.As(err, &ue) {
+				err = ue.Err
+			}
+			return nil, fmt.Errorf("invalid %s: %w", EnvDatabaseURL, err)
+		}
+		c = pc
+	}
+
+	dialect := c.Dialect
+	if d, ok := env[EnvConfigPrefix+"DIALECT"]; ok {
+		dialect = d
+	}
+	raw := envToRaw(env, EnvConfigPrefix, configType, GetFormalDialect(dialect))
+	if err := decodeConfig("", raw, c); err != nil {
+		return nil, fmt.Errorf("unable to load config from environment: %w", err)
+	}
+	return c, nil
+}
+
+func configFormatOf(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ConfigFormatYAML, nil
+	case ".json":
+		return ConfigFormatJSON, nil
+	case ".toml":
+		return ConfigFormatTOML, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedConfigFormat, path)
+	}
+}
+
+func unmarshalConfig(data []byte, format string) (map[string]any, error) {
+	raw := make(map[string]any)
+	var err error
+	switch format {
+	case ConfigFormatYAML:
+		err = yaml.Unmarshal(data, &raw)
+	case ConfigFormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&raw)
+	case ConfigFormatTOML:
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedConfigFormat, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s config: %w", format, err)
+	}
+	return raw, nil
+}
+
+// decodeConfig decodes the raw values into c, the fields absent from raw are kept.
+func decodeConfig(path string, raw map[string]any, c *Config) error {
+	return decodeStruct(path, raw, reflect.ValueOf(c).Elem())
+}
+
+func decodeValue(path string, in any, v reflect.Value) error {
+	if in == nil {
+		v.SetZero()
+		return nil
+	}
+
+	switch v.Type() {
+	case durationType:
+		d, err := toDuration(in)
+		if err != nil {
+			return &ConfigError{Path: path, Err: err}
+		}
+		v.SetInt(int64(d))
+		return nil
+	case locationType:
+		s, ok := in.(string)
+		if !ok {
+			return &ConfigError{Path: path, Err: fmt.Errorf("expected time zone name, got %T", in)}
+		}
+		loc, err := time.LoadLocation(s)
+		if err != nil {
+			return &ConfigError{Path: path, Err: err}
+		}
+		v.Set(reflect.ValueOf(loc))
+		return nil
+	case hostPortType:
+		if s, ok := in.(string); ok {
+			hp, err := parseHostPort(s)
+			if err != nil {
+				return &ConfigError{Path: path, Err: err}
+			}
+			v.Set(reflect.ValueOf(hp))
+			return nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeValue(path, in, v.Elem())
+	case reflect.Struct:
+		m, ok := in.(map[string]any)
+		if !ok {
+			return &ConfigError{Path: path, Err: fmt.Errorf("expected object, got %T", in)}
+		}
+		return decodeStruct(path, m, v)
+	case reflect.Slice:
+		items, ok := in.([]any)
+		if !ok {
+			s, ok := in.(string)
+			if !ok {
+				return &ConfigError{Path: path, Err: fmt.Errorf("expected list, got %T", in)}
+			}
+			// A string is a comma separated list, e.g. of environment variables.
+			for _, item := range strings.Split(s, ",") {
+				items = append(items, strings.TrimSpace(item))
+			}
+		}
+		sv := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(fmt.Sprintf("%s[%d]", path, i), item, sv.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(sv)
+		return nil
+	case reflect.Map:
+		m, ok := in.(map[string]any)
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return &ConfigError{Path: path, Err: fmt.Errorf("expected object, got %T", in)}
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(m)))
+		}
+		for _, k := range sortedKeys(m) {
+			ev := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeValue(joinConfigPath(path, k), m[k], ev); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), ev)
+		}
+		return nil
+	case reflect.Interface:
+		rv := reflect.ValueOf(in)
+		if !rv.Type().AssignableTo(v.Type()) {
+			return &ConfigError{Path: path, Err: fmt.Errorf("unsupported type %s", v.Type())}
+		}
+		v.Set(rv)
+		return nil
+	}
+
+	var err error
+	switch v.Kind() {
+	case reflect.String:
+		var s string
+		if s, err = toString(in); err == nil {
+			v.SetString(s)
+		}
+	case reflect.Bool:
+		var b bool
+		if b, err = toBool(in); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, err = toInt64(in); err == nil {
+			if v.OverflowInt(n) {
+				err = fmt.Errorf("%d overflows %s", n, v.Type())
+			} else {
+				v.SetInt(n)
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n int64
+		if n, err = toInt64(in); err == nil {
+			if n < 0 || v.OverflowUint(uint64(n)) {
+				err = fmt.Errorf("%d overflows %s", n, v.Type())
+			} else {
+				v.SetUint(uint64(n))
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = toFloat64(in); err == nil {
+			v.SetFloat(f)
+		}
+	default:
+		err = fmt.Errorf("unsupported type %s", v.Type())
+	}
+	if err != nil {
+		return &ConfigError{Path: path, Err: err}
+	}
+	return nil
+}
+
+// decodeStruct matches the keys to fields by their yaml tags or names, ignoring case and underscores.
+// Config.Extra is decoded after the other fields, so its type can be picked by the dialect.
+func decodeStruct(path string, m map[string]any, v reflect.Value) error {
+	fields := make(map[string]int)
+	for i := range v.NumField() {
+		f := v.Type().Field(i)
+		if key, ok := configFieldKey(f); ok {
+			fields[normalizeConfigKey(key)] = i
+		}
+	}
+
+	var extra any
+	var hasExtra bool
+	for _, k := range sortedKeys(m) {
+		i, ok := fields[normalizeConfigKey(k)]
+		if !ok {
+			return &ConfigError{Path: joinConfigPath(path, k), Err: errors.New("unknown field")}
+		}
+		if v.Type() == configType && v.Type().Field(i).Name == "Extra" {
+			extra, hasExtra = m[k], true
+			continue
+		}
+		if err := decodeValue(joinConfigPath(path, k), m[k], v.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	if hasExtra {
+		c := v.Addr().Interface().(*Config)
+		t, ok := extraTypes[GetFormalDialect(c.Dialect)]
+		if !ok || extra == nil {
+			c.Extra = extra
+			return nil
+		}
+		ev := reflect.New(t)
+		if err := decodeValue(joinConfigPath(path, "extra"), extra, ev.Elem()); err != nil {
+			return err
+		}
+		c.Extra = ev.Interface()
+	}
+	return nil
+}
+
+// envToRaw collects the environment variables of the fields of t.
+func envToRaw(env map[string]string, prefix string, t reflect.Type, dialect string) map[string]any {
+	raw := make(map[string]any)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		key, ok := configFieldKey(f)
+		if !ok {
+			continue
+		}
+		name := prefix + strings.ToUpper(toSnakeCase(key))
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer && ft != locationType {
+			ft = ft.Elem()
+		}
+		switch {
+		case t == configType && f.Name == "Extra":
+			if et, ok := extraTypes[dialect]; ok {
+				if nested := envToRaw(env, name+"_", et, dialect); len(nested) > 0 {
+					raw[key] = nested
+				}
+			}
+		case ft.Kind() == reflect.Struct && ft != hostPortType:
+			if nested := envToRaw(env, name+"_", ft, dialect); len(nested) > 0 {
+				raw[key] = nested
+			}
+		case ft.Kind() == reflect.Map:
+			// The keys of map are lower cased, e.g. HYPERSQL_PARAMS_SSLMODE for sslmode.
+			nested := make(map[string]any)
+			for k, v := range env {
+				if mk, ok := strings.CutPrefix(k, name+"_"); ok && len(mk) > 0 {
+					nested[strings.ToLower(mk)] = v
+				}
+			}
+			if len(nested) > 0 {
+				raw[key] = nested
+			}
+		default:
+			if v, ok := env[name]; ok {
+				raw[key] = v
+			}
+		}
+	}
+	return raw
+}
+
+// configFieldKey returns the yaml tag name of the field, or the snake cased name if it has no tag.
+// ok is false if the field is not configurable.
+func configFieldKey(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	switch tag {
+	case "-":
+		return "", false
+	case "":
+		return toSnakeCase(f.Name), true
+	default:
+		return tag, true
+	}
+}
+
+func normalizeConfigKey(k string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(k))
+}
+
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// A new word starts after a lower case letter, or before one in an acronym, e.g. "SQLServer".
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func joinConfigPath(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func toDuration(in any) (time.Duration, error) {
+	if s, ok := in.(string); ok {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Duration(n), nil
+		}
+		return time.ParseDuration(s)
+	}
+	n, err := toInt64(in)
+	if err != nil {
+		return 0, fmt.Errorf("expected duration such as 30s, got %T", in)
+	}
+	return time.Duration(n), nil
+}
+
+func toString(in any) (string, error) {
+	switch s := in.(type) {
+	case string:
+		return s, nil
+	case bool, int, int64, uint64, float64, json.Number:
+		return fmt.Sprint(s), nil
+	default:
+		return "", fmt.Errorf("expected string, got %T", in)
+	}
+}
+
+func toBool(in any) (bool, error) {
+	switch b := in.(type) {
+	case bool:
+		return b, nil
+	case string:
+		return strconv.ParseBool(b)
+	default:
+		return false, fmt.Errorf("expected bool, got %T", in)
+	}
+}
+
+func toInt64(in any) (int64, error) {
+	switch n := in.(type) {
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, fmt.Errorf("%d overflows int64", n)
+		}
+		return int64(n), nil
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, fmt.Errorf("expected integer, got %v", n)
+		}
+		return int64(n), nil
+	case json.Number:
+		return n.Int64()
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	default:
+		return 0, fmt.Errorf("expected integer, got %T", in)
+	}
+}
+
+func toFloat64(in any) (float64, error) {
+	switch n := in.(type) {
+	case float64:
+		return n, nil
+	case int, int64, uint64:
+		i, err := toInt64(n)
+		return float64(i), err
+	case json.Number:
+		return n.Float64()
+	case string:
+		return strconv.ParseFloat(n, 64)
+	default:
+		return 0, fmt.Errorf("expected number, got %T", in)
+	}
+}
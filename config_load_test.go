@@ -0,0 +1,187 @@
+package hypersql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testYAMLConfig = `
+dialect: postgres
+host: localhost
+port: 5432
+name: app
+user: app
+password: env:DB_PASSWORD
+dial_timeout: 5s
+conn_max_lifetime: 1h
+max_open_conns: 20
+loc: Asia/Shanghai
+hosts:
+  - host: h1
+    port: 5432
+  - h2:5433
+params:
+  sslmode: disable
+  connect_timeout: 10
+tls_cert:
+  ca_file: file:/etc/ssl/ca.pem
+health_thresholds:
+  ping_latency_degraded: 100ms
+  in_use_ratio_degraded: 0.8
+debug: true
+extra:
+  statement_cache_capacity: 128
+`
+
+const testTOMLConfig = `
+dialect = "mysql"
+host = "localhost"
+port = 3306
+dial_timeout = "5s"
+conn_max_idle_time = 60000000000
+
+[params]
+charset = "utf8mb4"
+
+[extra]
+MultiStatements = true
+`
+
+const testJSONConfig = `{
+	"dialect": "sqlserver",
+	"port": 1433,
+	"max_idle_conns": 4,
+	"conn_max_lifetime": "30m",
+	"loc": "UTC",
+	"extra": {}
+}`
+
+func TestParseConfig(t *testing.T) {
+	c, err := ParseConfig([]byte(testYAMLConfig), ConfigFormatYAML)
+	require.NoError(t, err)
+	assert.Equal(t, DialectPostgres, c.Dialect)
+	assert.Equal(t, 5432, c.Port)
+	assert.Equal(t, "env:DB_PASSWORD", c.Password)
+	assert.Equal(t, 5*time.Second, c.DialTimeout)
+	assert.Equal(t, time.Hour, c.ConnMaxLifetime)
+	assert.Equal(t, 20, c.MaxOpenConns)
+	assert.Equal(t, "Asia/Shanghai", c.Loc.String())
+	assert.Equal(t, []HostPort{{Host: "h1", Port: 5432}, {Host: "h2", Port: 5433}}, c.Hosts)
+	assert.Equal(t, ConfigParams{"sslmode": "disable", "connect_timeout": "10"}, c.Params)
+	assert.Equal(t, "file:/etc/ssl/ca.pem", c.TLSCert.CAFile)
+	assert.Equal(t, 100*time.Millisecond, c.HealthThresholds.PingLatencyDegraded)
+	assert.InDelta(t, 0.8, c.HealthThresholds.InUseRatioDegraded, 0.001)
+	assert.True(t, c.Debug)
+	require.IsType(t, &PostgresExtra{}, c.Extra)
+	assert.Equal(t, 128, c.Extra.(*PostgresExtra).StatementCacheCapacity)
+
+	c, err = ParseConfig([]byte(testTOMLConfig), ConfigFormatTOML)
+	require.NoError(t, err)
+	assert.Equal(t, DialectMySQL, c.Dialect)
+	assert.Equal(t, 5*time.Second, c.DialTimeout)
+	assert.Equal(t, time.Minute, c.ConnMaxIdleTime)
+	assert.Equal(t, "utf8mb4", c.Params["charset"])
+	require.IsType(t, &MySQLExtra{}, c.Extra)
+	assert.True(t, c.Extra.(*MySQLExtra).MultiStatements)
+
+	c, err = ParseConfig([]byte(testJSONConfig), ConfigFormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, DialectSQLServer, c.Dialect)
+	assert.Equal(t, 1433, c.Port)
+	assert.Equal(t, 4, c.MaxIdleConns)
+	assert.Equal(t, 30*time.Minute, c.ConnMaxLifetime)
+	assert.Equal(t, time.UTC, c.Loc)
+	assert.IsType(t, &SQLServerExtra{}, c.Extra)
+}
+
+func TestParseConfig_Errors(t *testing.T) {
+	cases := []struct {
+		data string
+		path string
+	}{
+		{data: "dial_timeout: soon", path: "dial_timeout"},
+		{data: "max_open_conns: many", path: "max_open_conns"},
+		{data: "loc: Mars/Olympus", path: "loc"},
+		{data: "hosts: [{host: h1, port: x}]", path: "hosts[0].port"},
+		{data: "tls_cert: {ca_optional: maybe}", path: "tls_cert.ca_optional"},
+		{data: "passwd: secret", path: "passwd"},
+		{data: "dialect: postgres\nextra: {dial_func: x}", path: "extra.dial_func"},
+	}
+	for _, tc := range cases {
+		_, err := ParseConfig([]byte(tc.data), ConfigFormatYAML)
+		var ce *ConfigError
+		require.ErrorAs(t, err, &ce, tc.data)
+		assert.Equal(t, tc.path, ce.Path)
+		assert.Contains(t, err.Error(), tc.path)
+	}
+
+	_, err := ParseConfig([]byte("{}"), "ini")
+	require.ErrorIs(t, err, ErrUnsupportedConfigFormat)
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"db.yaml": testYAMLConfig,
+		"db.toml": testTOMLConfig,
+		"db.json": testJSONConfig,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		c, err := LoadConfig(path)
+		require.NoError(t, err, name)
+		assert.NotEmpty(t, c.Dialect)
+	}
+
+	_, err := LoadConfig(filepath.Join(dir, "db.ini"))
+	require.ErrorIs(t, err, ErrUnsupportedConfigFormat)
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	c, err := loadConfigFromEnv([]string{
+		"DATABASE_URL=postgres://app:secret@h1:5432/app?sslmode=disable",
+		"HYPERSQL_MAX_OPEN_CONNS=8",
+		"HYPERSQL_CONN_MAX_LIFETIME=15m",
+		"HYPERSQL_LOC=UTC",
+		"HYPERSQL_HOSTS=h1:5432,h2:5433",
+		"HYPERSQL_PARAMS_APPLICATION_NAME=api",
+		"HYPERSQL_TLS_CERT_CA_FILE=file:/etc/ssl/ca.pem",
+		"HYPERSQL_EXTRA_STATEMENT_CACHE_CAPACITY=64",
+		"OTHER=ignored",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, DialectPostgres, c.Dialect)
+	assert.Equal(t, "app", c.User)
+	assert.Equal(t, "secret", c.Password)
+	assert.Equal(t, 8, c.MaxOpenConns)
+	assert.Equal(t, 15*time.Minute, c.ConnMaxLifetime)
+	assert.Equal(t, time.UTC, c.Loc)
+	assert.Equal(t, []HostPort{{Host: "h1", Port: 5432}, {Host: "h2", Port: 5433}}, c.Hosts)
+	assert.Equal(t, "disable", c.Params["sslmode"])
+	assert.Equal(t, "api", c.Params["application_name"])
+	assert.Equal(t, "file:/etc/ssl/ca.pem", c.TLSCert.CAFile)
+	require.IsType(t, &PostgresExtra{}, c.Extra)
+	assert.Equal(t, 64, c.Extra.(*PostgresExtra).StatementCacheCapacity)
+
+	_, err = loadConfigFromEnv([]string{"HYPERSQL_DIALECT=mysql", "HYPERSQL_PORT=abc"})
+	var ce *ConfigError
+	require.ErrorAs(t, err, &ce)
+	assert.Equal(t, "port", ce.Path)
+
+	_, err = loadConfigFromEnv([]string{"DATABASE_URL=postgres://app:s3cr3t@h1:bad/app"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "s3cr3t")
+}
+
+func TestToSnakeCase(t *testing.T) {
+	assert.Equal(t, "statement_cache_capacity", toSnakeCase("StatementCacheCapacity"))
+	assert.Equal(t, "server_pub_key", toSnakeCase("ServerPubKey"))
+	assert.Equal(t, "sql_server_extra", toSnakeCase("SQLServerExtra"))
+	assert.Equal(t, "debug", toSnakeCase("Debug"))
+}
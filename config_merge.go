@@ -0,0 +1,227 @@
+package hypersql
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+)
+
+var ErrUnknownProfile = errors.New("unknown config profile")
+
+// appendedConfigFields are the fields that Merge appends the overlay to, instead of replacing.
+var appendedConfigFields = []string{"DriverHooks", "DriverWrappers", "AfterHandlers", "CloseHandlers"}
+
+// ConfigProfiles holds a base config and the named overlays of it, e.g. dev, test and prod.
+type ConfigProfiles struct {
+	Base     *Config
+	Profiles map[string]*Config
+
+	// fields holds the names of the fields set by the parsed profiles, Resolve applies exactly them.
+	fields map[string][]string
+}
+
+// Clone returns a deep copy of the config. Params, Hosts, TLSCert, TLSConfig, HealthThresholds,
+// hooks, wrappers and handlers are copied, and Extra is copied if it is a pointer to struct.
+// Loc, Logger and CredentialProvider are shared.
+func (c *Config) Clone() *Config {
+	if c == nil {
+		return nil
+	}
+	cc := *c
+	cc.Params = maps.Clone(c.Params)
+	cc.Hosts = slices.Clone(c.Hosts)
+	if c.TLSCert != nil {
+		tc := *c.TLSCert
+		tc.CipherSuites = slices.Clone(tc.CipherSuites)
+		cc.TLSCert = &tc
+	}
+	if c.TLSConfig != nil {
+		cc.TLSConfig = c.TLSConfig.Clone()
+	}
+	if c.HealthThresholds != nil {
+		ht := *c.HealthThresholds
+		cc.HealthThresholds = &ht
+	}
+	cc.DriverHooks = slices.Clone(c.DriverHooks)
+	cc.DriverWrappers = slices.Clone(c.DriverWrappers)
+	cc.AfterHandlers = slices.Clone(c.AfterHandlers)
+	cc.CloseHandlers = slices.Clone(c.CloseHandlers)
+	cc.Extra = cloneExtra(c.Extra)
+	return &cc
+}
+
+// Merge returns a new config of c overlaid by overlay, neither of them is modified. The precedence rules are:
+//   - the non-zero fields of overlay replace the ones of c, so zero values and false can not unset them,
+//   - Params are merged by key and the overlay ones win,
+//   - Hosts, TLSCert, TLSConfig, HealthThresholds and Extra are replaced as a whole,
+//   - DriverHooks, DriverWrappers, AfterHandlers and CloseHandlers of overlay are appended to the ones of c.
+//
+// The profiles parsed by ParseConfigProfiles are resolved by the same rules, except that
+// the fields they set are applied even if they are zero.
+func (c *Config) Merge(overlay *Config) *Config {
+	return c.merge(overlay, func(name string, v reflect.Value) bool {
+		return !v.IsZero()
+	})
+}
+
+// merge overlays the fields that applied reports.
+func (c *Config) merge(overlay *Config, applied func(name string, v reflect.Value) bool) *Config {
+	m := c.Clone()
+	if m == nil {
+		m = new(Config)
+	}
+	if overlay == nil {
+		return m
+	}
+	o := overlay.Clone()
+
+	mv := reflect.ValueOf(m).Elem()
+	ov := reflect.ValueOf(o).Elem()
+	for i := range ov.NumField() {
+		of := ov.Field(i)
+		name := ov.Type().Field(i).Name
+		if !applied(name, of) {
+			continue
+		}
+		switch {
+		case name == "Params":
+			if m.Params == nil {
+				m.Params = make(ConfigParams, len(o.Params))
+			}
+			maps.Copy(m.Params, o.Params)
+		case slices.Contains(appendedConfigFields, name):
+			mv.Field(i).Set(reflect.AppendSlice(mv.Field(i), of))
+		default:
+			mv.Field(i).Set(of)
+		}
+	}
+	return m
+}
+
+// Resolve returns the base config merged with the named profile, the base one is returned if name is empty.
+func (p *ConfigProfiles) Resolve(name string) (*Config, error) {
+	if len(name) == 0 {
+		return p.Base.Merge(nil), nil
+	}
+	overlay, ok := p.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+	fields, ok := p.fields[name]
+	if !ok {
+		return p.Base.Merge(overlay), nil
+	}
+	return p.Base.merge(overlay, func(name string, _ reflect.Value) bool {
+		return slices.Contains(fields, name)
+	}), nil
+}
+
+// Names returns the sorted names of profiles.
+func (p *ConfigProfiles) Names() []string {
+	return slices.Sorted(maps.Keys(p.Profiles))
+}
+
+// LoadConfigProfiles loads the profiles from a YAML, JSON or TOML file, see ParseConfigProfiles.
+func LoadConfigProfiles(path string) (*ConfigProfiles, error) {
+	format, err := configFormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParseConfigProfiles(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load config profiles file %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParseConfigProfiles parses the base config under "base" and the overlays under "profiles", e.g.
+//
+//	base:
+//	  dialect: postgres
+//	  host: localhost
+//	profiles:
+//	  prod:
+//	    host: db.internal
+//	    max_open_conns: 50
+//
+// The configs are decoded as ParseConfig does, and the dialect of base is used to decode Extra of overlays.
+// The fields set by a profile replace the ones of base even if they are zero, e.g. debug: false.
+func ParseConfigProfiles(data []byte, format string) (*ConfigProfiles, error) {
+	raw, err := unmarshalConfig(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ConfigProfiles{Base: new(Config), Profiles: make(map[string]*Config), fields: make(map[string][]string)}
+	for _, k := range sortedKeys(raw) {
+		switch k {
+		case "base":
+			m, ok := raw[k].(map[string]any)
+			if !ok && raw[k] != nil {
+				return nil, &ConfigError{Path: k, Err: fmt.Errorf("expected object, got %T", raw[k])}
+			}
+			if err := decodeConfig(k, m, p.Base); err != nil {
+				return nil, err
+			}
+		case "profiles":
+		default:
+			return nil, &ConfigError{Path: k, Err: errors.New("unknown field")}
+		}
+	}
+
+	profiles, ok := raw["profiles"].(map[string]any)
+	if !ok && raw["profiles"] != nil {
+		return nil, &ConfigError{Path: "profiles", Err: fmt.Errorf("expected object, got %T", raw["profiles"])}
+	}
+	for _, name := range sortedKeys(profiles) {
+		path := joinConfigPath("profiles", name)
+		m, ok := profiles[name].(map[string]any)
+		if !ok && profiles[name] != nil {
+			return nil, &ConfigError{Path: path, Err: fmt.Errorf("expected object, got %T", profiles[name])}
+		}
+		// The dialect is kept by Merge, it only picks the type of Extra.
+		c := &Config{Dialect: p.Base.Dialect}
+		if err := decodeConfig(path, m, c); err != nil {
+			return nil, err
+		}
+		p.Profiles[name] = c
+		p.fields[name] = configFieldNames(m)
+	}
+	return p, nil
+}
+
+// configFieldNames returns the names of the Config fields set by the keys of raw.
+func configFieldNames(raw map[string]any) []string {
+	names := make(map[string]string)
+	for i := range configType.NumField() {
+		f := configType.Field(i)
+		if key, ok := configFieldKey(f); ok {
+			names[normalizeConfigKey(key)] = f.Name
+		}
+	}
+	fields := make([]string, 0, len(raw))
+	for k := range raw {
+		if name, ok := names[normalizeConfigKey(k)]; ok {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// cloneExtra copies the struct if extra is a pointer to struct, otherwise extra is returned as is.
+func cloneExtra(extra any) any {
+	v := reflect.ValueOf(extra)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return extra
+	}
+	cv := reflect.New(v.Elem().Type())
+	cv.Elem().Set(v.Elem())
+	return cv.Interface()
+}
@@ -0,0 +1,170 @@
+package hypersql
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Clone(t *testing.T) {
+	wrapper := func(drv driver.Driver) driver.Driver { return drv }
+	c := &Config{
+		Dialect:          DialectPostgres,
+		Params:           ConfigParams{"sslmode": "disable"},
+		Hosts:            []HostPort{{Host: "h1", Port: 5432}},
+		TLSCert:          &TLSCert{CAFile: "/ca.pem"},
+		TLSConfig:        &tls.Config{ServerName: "h1"},
+		HealthThresholds: &HealthThresholds{InUseRatioDegraded: 0.8},
+		DriverWrappers:   DriverWrappers{wrapper},
+		Extra:            &PostgresExtra{StatementCacheCapacity: 64},
+	}
+
+	cc := c.Clone()
+	require.NotSame(t, c, cc)
+	cc.Params["sslmode"] = "require"
+	cc.Hosts[0].Host = "h2"
+	cc.TLSCert.CAFile = "/other.pem"
+	cc.TLSConfig.ServerName = "h2"
+	cc.HealthThresholds.InUseRatioDegraded = 0.5
+	cc.DriverWrappers = append(cc.DriverWrappers[:0], wrapper, wrapper)
+	cc.Extra.(*PostgresExtra).StatementCacheCapacity = 32
+
+	assert.Equal(t, "disable", c.Params["sslmode"])
+	assert.Equal(t, "h1", c.Hosts[0].Host)
+	assert.Equal(t, "/ca.pem", c.TLSCert.CAFile)
+	assert.Equal(t, "h1", c.TLSConfig.ServerName)
+	assert.InDelta(t, 0.8, c.HealthThresholds.InUseRatioDegraded, 0.001)
+	assert.Len(t, c.DriverWrappers, 1)
+	assert.Equal(t, 64, c.Extra.(*PostgresExtra).StatementCacheCapacity)
+
+	var nc *Config
+	assert.Nil(t, nc.Clone())
+}
+
+func TestConfig_Merge(t *testing.T) {
+	h1 := func(context.Context, *sql.DB) error { return nil }
+	h2 := func(context.Context, *sql.DB) error { return nil }
+	base := &Config{
+		Dialect:       DialectPostgres,
+		Host:          "localhost",
+		Port:          5432,
+		User:          "app",
+		MaxOpenConns:  10,
+		DialTimeout:   5 * time.Second,
+		Debug:         true,
+		Params:        ConfigParams{"sslmode": "disable", "application_name": "app"},
+		TLSCert:       &TLSCert{CAFile: "/base.pem", InsecureSkipVerify: true},
+		AfterHandlers: AfterHandlers{h1},
+	}
+	overlay := &Config{
+		Host:          "db.internal",
+		MaxOpenConns:  50,
+		Params:        ConfigParams{"sslmode": "verify-full"},
+		TLSCert:       &TLSCert{CAFile: "/prod.pem"},
+		AfterHandlers: AfterHandlers{h2},
+	}
+
+	m := base.Merge(overlay)
+	assert.Equal(t, DialectPostgres, m.Dialect)
+	assert.Equal(t, "db.internal", m.Host)
+	assert.Equal(t, 5432, m.Port)
+	assert.Equal(t, "app", m.User)
+	assert.Equal(t, 50, m.MaxOpenConns)
+	assert.Equal(t, 5*time.Second, m.DialTimeout)
+	assert.True(t, m.Debug)
+	assert.Equal(t, ConfigParams{"sslmode": "verify-full", "application_name": "app"}, m.Params)
+	// TLSCert is replaced as a whole.
+	assert.Equal(t, &TLSCert{CAFile: "/prod.pem"}, m.TLSCert)
+	assert.Len(t, m.AfterHandlers, 2)
+
+	// Neither of them is modified.
+	assert.Equal(t, "localhost", base.Host)
+	assert.Equal(t, "disable", base.Params["sslmode"])
+	assert.Len(t, base.AfterHandlers, 1)
+	assert.Equal(t, ConfigParams{"sslmode": "verify-full"}, overlay.Params)
+	m.Params["sslmode"] = "require"
+	assert.Equal(t, "verify-full", overlay.Params["sslmode"])
+
+	assert.Equal(t, base.Host, base.Merge(nil).Host)
+	var nc *Config
+	assert.Equal(t, "db.internal", nc.Merge(overlay).Host)
+}
+
+const testProfilesYAML = `
+base:
+  dialect: postgres
+  host: localhost
+  port: 5432
+  max_open_conns: 10
+  debug: true
+  params:
+    sslmode: disable
+  extra:
+    statement_cache_capacity: 64
+profiles:
+  test:
+    name: app_test
+  prod:
+    host: db.internal
+    max_open_conns: 50
+    params:
+      sslmode: verify-full
+    extra:
+      statement_cache_capacity: 256
+  unlimited:
+    max_open_conns: 0
+    debug: false
+`
+
+func TestConfigProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testProfilesYAML), 0o600))
+	p, err := LoadConfigProfiles(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod", "test", "unlimited"}, p.Names())
+
+	c, err := p.Resolve("")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", c.Host)
+
+	c, err = p.Resolve("test")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", c.Host)
+	assert.Equal(t, "app_test", c.Name)
+	assert.Equal(t, 64, c.Extra.(*PostgresExtra).StatementCacheCapacity)
+
+	c, err = p.Resolve("prod")
+	require.NoError(t, err)
+	assert.Equal(t, DialectPostgres, c.Dialect)
+	assert.Equal(t, "db.internal", c.Host)
+	assert.Equal(t, 5432, c.Port)
+	assert.Equal(t, 50, c.MaxOpenConns)
+	assert.Equal(t, "verify-full", c.Params["sslmode"])
+	assert.Equal(t, 256, c.Extra.(*PostgresExtra).StatementCacheCapacity)
+	// Resolving does not modify the base.
+	assert.Equal(t, "disable", p.Base.Params["sslmode"])
+
+	// The zero values set by the profile are applied.
+	c, err = p.Resolve("unlimited")
+	require.NoError(t, err)
+	assert.Zero(t, c.MaxOpenConns)
+	assert.False(t, c.Debug)
+	assert.Equal(t, "localhost", c.Host)
+	assert.Equal(t, "disable", c.Params["sslmode"])
+
+	_, err = p.Resolve("staging")
+	require.ErrorIs(t, err, ErrUnknownProfile)
+
+	_, err = ParseConfigProfiles([]byte("profiles:\n  prod:\n    max_open_conns: many\n"), ConfigFormatYAML)
+	var ce *ConfigError
+	require.ErrorAs(t, err, &ce)
+	assert.Equal(t, "profiles.prod.max_open_conns", ce.Path)
+}
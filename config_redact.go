@@ -0,0 +1,197 @@
+package hypersql
+
+import (
+	"log/slog"
+	"maps"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// RedactedSecret replaces the secrets in redacted Config.
+const RedactedSecret = "******"
+
+var (
+	_ slog.LogValuer = (*Config)(nil)
+
+	// dsnKeyValueRegexp matches the key and value pairs of key=value DSNs, ADO connection strings and URL queries,
+	// the value may be single quoted.
+	dsnKeyValueRegexp = regexp.MustCompile(`(?:^|[\s;?&])([A-Za-z_][\w.\- ]*?)\s*=\s*('(?:[^'\\]|\\.)*'|[^\s;&]*)`)
+)
+
+// IsSecretParam reports whether the param holds a secret, e.g. password, sslpassword or _auth_pass.
+func IsSecretParam(key string) bool {
+	k := normalizeConfigKey(key)
+	switch {
+	case k == "pwd", k == "authpass":
+		return true
+	case strings.Contains(k, "password"), strings.Contains(k, "passwd"),
+		strings.Contains(k, "secret"), strings.Contains(k, "token"):
+		return true
+	default:
+		return false
+	}
+}
+
+// Redacted returns a copy of the config whose secrets are masked, it is safe to be exposed.
+// The values of secret params are masked, and the passwords in the DSN or URL values of the others too.
+func (c *Config) Redacted() *Config {
+	if c == nil {
+		return nil
+	}
+	rc := *c
+	if len(rc.Password) > 0 {
+		rc.Password = RedactedSecret
+	}
+	if t := rc.TLSCert; t != nil {
+		tc := *t
+		if isPEMContent(tc.KeyFile) || strings.HasPrefix(tc.KeyFile, SecretSchemeBase64+":") {
+			tc.KeyFile = RedactedSecret
+		}
+		rc.TLSCert = &tc
+	}
+	if rc.Params != nil {
+		rc.Params = maps.Clone(c.Params)
+		for k, v := range rc.Params {
+			if len(v) > 0 && IsSecretParam(k) {
+				rc.Params[k] = RedactedSecret
+			} else if rv := RedactDSN(v); rv != v {
+				rc.Params[k] = rv
+			}
+		}
+	}
+	return &rc
+}
+
+// String formats the redacted config like a URL, e.g. postgres://user:******@h1:5432,h2:5432/db?sslmode=disable.
+func (c *Config) String() string {
+	if c == nil {
+		return "<nil>"
+	}
+	rc := c.Redacted()
+
+	var hosts []string
+	for _, h := range rc.HostPorts() {
+		if len(h.Host) > 0 {
+			hosts = append(hosts, h.String())
+		}
+	}
+	u := &url.URL{
+		Scheme:   rc.Dialect,
+		Host:     strings.Join(hosts, ","),
+		Path:     "/" + strings.TrimPrefix(rc.Name, "/"),
+		RawQuery: url.Values(paramValues(rc.Params)).Encode(),
+	}
+	if len(rc.User) > 0 || len(rc.Password) > 0 {
+		if len(rc.Password) > 0 {
+			u.User = url.UserPassword(rc.User, rc.Password)
+		} else {
+			u.User = url.User(rc.User)
+		}
+	}
+	return u.String()
+}
+
+// LogValue logs the redacted config as a group, the funcs and hooks are omitted.
+func (c *Config) LogValue() slog.Value {
+	if c == nil {
+		return slog.StringValue("<nil>")
+	}
+	rc := c.Redacted()
+
+	hosts := make([]string, 0, len(rc.Hosts)+1)
+	for _, h := range rc.HostPorts() {
+		hosts = append(hosts, h.String())
+	}
+	attrs := []slog.Attr{
+		slog.String("dialect", rc.Dialect),
+		slog.String("transport", rc.Transport),
+		slog.String("hosts", strings.Join(hosts, ",")),
+		slog.String("name", rc.Name),
+		slog.String("user", rc.User),
+	}
+	if len(rc.Password) > 0 {
+		attrs = append(attrs, slog.String("password", rc.Password))
+	}
+	if rc.CredentialProvider != nil {
+		attrs = append(attrs, slog.Bool("credential_provider", true))
+	}
+	if len(rc.Params) > 0 {
+		params := make([]slog.Attr, 0, len(rc.Params))
+		for _, k := range sortedParamKeys(rc.Params) {
+			params = append(params, slog.String(k, rc.Params[k]))
+		}
+		attrs = append(attrs, slog.Attr{Key: "params", Value: slog.GroupValue(params...)})
+	}
+	if rc.TLSConfig != nil || rc.TLSCert != nil {
+		attrs = append(attrs, slog.Bool("tls", true))
+	}
+	if rc.DialTimeout > 0 {
+		attrs = append(attrs, slog.Duration("dial_timeout", rc.DialTimeout))
+	}
+	attrs = append(attrs,
+		slog.Int("max_open_conns", rc.MaxOpenConns),
+		slog.Int("max_idle_conns", rc.MaxIdleConns),
+		slog.Duration("conn_max_lifetime", rc.ConnMaxLifetime),
+		slog.Duration("conn_max_idle_time", rc.ConnMaxIdleTime),
+	)
+	return slog.GroupValue(attrs...)
+}
+
+// RedactDSN masks the password and secret params of the DSN, e.g. the one of ToPostgresDSN or ToMySQLDSN.
+// The URL, MySQL, key=value and ADO connection string forms are supported.
+func RedactDSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		if u, err := url.Parse(dsn); err == nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), RedactedSecret)
+			}
+			dsn = u.String()
+		}
+	} else if cc, err := mysql.ParseDSN(dsn); err == nil && len(cc.Passwd) > 0 {
+		// The password of MySQL DSN is between the first colon and the last at sign before the last slash,
+		// as mysql.ParseDSN does.
+		slash := strings.LastIndex(dsn, "/")
+		if at := strings.LastIndex(dsn[:slash], "@"); at > 0 {
+			if colon := strings.Index(dsn[:at], ":"); colon >= 0 {
+				dsn = dsn[:colon+1] + RedactedSecret + dsn[at:]
+			}
+		}
+	}
+	return redactDSNParams(dsn)
+}
+
+// redactDSNParams masks the values of secret keys in key=value pairs.
+func redactDSNParams(dsn string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range dsnKeyValueRegexp.FindAllStringSubmatchIndex(dsn, -1) {
+		key := dsn[m[2]:m[3]]
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if !IsSecretParam(strings.TrimSpace(key)) || m[4] == m[5] {
+			continue
+		}
+		b.WriteString(dsn[last:m[4]])
+		b.WriteString(RedactedSecret)
+		last = m[5]
+	}
+	b.WriteString(dsn[last:])
+	return b.String()
+}
+
+func paramValues(p ConfigParams) map[string][]string {
+	values := make(map[string][]string, len(p))
+	for k, v := range p {
+		values[k] = []string{v}
+	}
+	return values
+}
+
+// isPEMContent reports whether the value is the PEM content instead of a file path.
+func isPEMContent(v string) bool {
+	return strings.Contains(v, "-----BEGIN")
+}
@@ -8,6 +8,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/sanity-io/litter"
 	"github.com/stretchr/testify/assert"
@@ -29,6 +30,25 @@ func TestOptions_Validate(t *testing.T) {
 	assert.ErrorIs(t, verr, ErrUnsupportedDialect)
 }
 
+func TestConfig_DBInfo_Alias(t *testing.T) {
+	cases := map[string]string{
+		"pg":         DialectPostgres,
+		"postgresql": DialectPostgres,
+		"mssql":      DialectSQLServer,
+		"mysql5":     DialectMySQL,
+		DialectMySQL: DialectMySQL,
+		"unknown":    "unknown",
+	}
+	for alias, dialect := range cases {
+		info := (&Config{Dialect: alias, Name: "app"}).DBInfo()
+		assert.Equal(t, dialect, info.Dialect, alias)
+	}
+	info := (&Config{Dialect: "pg"}).DBInfo()
+	assert.NotNil(t, GetLagProbe(info.Dialect))
+	assert.Contains(t, readOnlyQueries, info.Dialect)
+	assert.Contains(t, versionQueries, info.Dialect)
+}
+
 func TestParseURL_1(t *testing.T) {
 	var ctx = context.Background()
 
@@ -123,3 +143,29 @@ func TestParseURL_1(t *testing.T) {
 		fmt.Println("DSN: ", dsn)
 	})
 }
+
+func TestParseManagerYAML(t *testing.T) {
+	configs, err := ParseManagerYAML([]byte(`
+users:
+  dialect: postgres
+  host: localhost
+  port: 5432
+  name: users
+  conn_max_lifetime: 5m
+orders:
+  dialect: mysql
+  hosts:
+    - host: db1
+      port: 3306
+    - host: db2
+`))
+	require.NoError(t, err)
+	require.Len(t, configs, 2)
+	assert.Equal(t, DialectPostgres, configs["users"].Dialect)
+	assert.Equal(t, 5432, configs["users"].Port)
+	assert.Equal(t, 5*time.Minute, configs["users"].ConnMaxLifetime)
+	assert.Equal(t, []HostPort{{Host: "db1", Port: 3306}, {Host: "db2"}}, configs["orders"].Hosts)
+
+	_, err = ParseManagerYAML([]byte("users: [1, 2]"))
+	require.Error(t, err)
+}
@@ -1,24 +1,397 @@
 package hypersql
 
 import (
+	"bytes"
+	"cmp"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
+)
+
+// The TLS modes follow sslmode of libpq, see https://www.postgresql.org/docs/current/libpq-ssl.html.
+const (
+	// TLSModeDisable connects without TLS.
+	TLSModeDisable = "disable"
+	// TLSModePrefer tries TLS without verification, and falls back to plaintext if the server does not support it.
+	TLSModePrefer = "prefer"
+	// TLSModeRequire requires TLS without verification, unless CAFile is set, then it is the same as verify-ca.
+	TLSModeRequire = "require"
+	// TLSModeVerifyCA requires TLS and verifies the server certificate is signed by a trusted CA.
+	TLSModeVerifyCA = "verify-ca"
+	// TLSModeVerifyFull verifies the server host name matches the certificate too.
+	TLSModeVerifyFull = "verify-full"
 )
 
 var (
 	ErrEmptyTLSConfig = fmt.Errorf("empty TLS config")
+
+	ErrInvalidTLSMode = errors.New("invalid TLS mode")
+
+	ErrCertificateRevoked = errors.New("certificate is revoked")
+
+	tlsVersions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
 )
 
+// TLSCert configures the TLS of client connections, the files are paths or PEM contents.
 type TLSCert struct {
-	CAFile             string `json:"ca_file" yaml:"ca_file"  toml:"ca_file"`
-	CAOptional         bool   `json:"ca_optional" yaml:"ca_optional"  toml:"ca_optional"`
-	CertFile           string `json:"cert_file" yaml:"cert_file"  toml:"cert_file"`
-	KeyFile            string `json:"key_file" yaml:"key_file" toml:"key_file"`
+	// Mode is one of disable, prefer, require, verify-ca and verify-full.
+	// It is verify-full by default, or require if InsecureSkipVerify is set.
+	Mode       string `json:"mode" yaml:"mode" toml:"mode"`
+	CAFile     string `json:"ca_file" yaml:"ca_file"  toml:"ca_file"`
+	CAOptional bool   `json:"ca_optional" yaml:"ca_optional"  toml:"ca_optional"`
+	CertFile   string `json:"cert_file" yaml:"cert_file"  toml:"cert_file"`
+	KeyFile    string `json:"key_file" yaml:"key_file" toml:"key_file"`
+	// ServerName is verified in verify-full mode instead of the host, and it is sent as SNI.
+	ServerName string `json:"server_name" yaml:"server_name" toml:"server_name"`
+	// MinVersion is the minimum TLS version, e.g. 1.2 or 1.3.
+	MinVersion string `json:"min_version" yaml:"min_version" toml:"min_version"`
+	// CipherSuites are the names of cipher suites for TLS 1.2 and older, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+	CipherSuites []string `json:"cipher_suites" yaml:"cipher_suites" toml:"cipher_suites"`
+	// CRLFile is the certificate revocation list in PEM or DER, the server certificates are checked against it.
+	CRLFile            string `json:"crl_file" yaml:"crl_file" toml:"crl_file"`
 	InsecureSkipVerify bool   `json:"insecure_skip_verify" yaml:"insecure_skip_verify" toml:"insecure_skip_verify"`
+	// Watch reloads CertFile, KeyFile, CAFile and CRLFile when they are changed, so new connections use the rotated ones.
+	// Only the paths are watched, see Config.ResolveSecrets.
+	Watch bool `json:"watch" yaml:"watch" toml:"watch"`
+	// WatchInterval is the minimum interval of checking the files, DefaultCertWatchInterval is used if it is zero.
+	WatchInterval time.Duration `json:"watch_interval" yaml:"watch_interval" toml:"watch_interval"`
+}
+
+// TLSMode returns the effective mode.
+func (t *TLSCert) TLSMode() (string, error) {
+	if t == nil {
+		return TLSModeDisable, nil
+	}
+	switch mode := strings.ToLower(t.Mode); mode {
+	case "":
+		if t.InsecureSkipVerify {
+			return TLSModeRequire, nil
+		}
+		return TLSModeVerifyFull, nil
+	case TLSModeDisable, TLSModePrefer, TLSModeRequire:
+		return mode, nil
+	case TLSModeVerifyCA, TLSModeVerifyFull:
+		if t.InsecureSkipVerify {
+			return "", fmt.Errorf("%w: %s conflicts with insecure_skip_verify", ErrInvalidTLSMode, mode)
+		}
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidTLSMode, t.Mode)
+	}
+}
+
+// Validate checks the mode, version and cipher suites, the files are not read.
+func (t *TLSCert) Validate() error {
+	if _, err := t.TLSMode(); err != nil {
+		return err
+	}
+	if _, err := parseTLSVersion(t.MinVersion); err != nil {
+		return err
+	}
+	_, err := parseCipherSuites(t.CipherSuites)
+	return err
+}
+
+// ClientTLSConfig creates the client TLS config of the mode, nil is returned in disable mode.
+// The server name is left empty unless ServerName is set, use it with tlsConfigForHost.
+func (t *TLSCert) ClientTLSConfig() (*tls.Config, error) {
+	mode, err := t.TLSMode()
+	if err != nil || mode == TLSModeDisable {
+		return nil, err
+	}
+	minVersion, err := parseTLSVersion(t.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := parseCipherSuites(t.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		ServerName:   t.ServerName,
+		MinVersion:   minVersion,
+		CipherSuites: cipherSuites,
+	}
+
+	var files func() *tlsFiles
+	if t.Watch {
+		w, err := newCertWatcher(t)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = w.clientCertificate
+		files = w.current
+	} else {
+		f, err := t.loadFiles()
+		if err != nil {
+			return nil, err
+		}
+		if f.cert != nil {
+			cfg.Certificates = []tls.Certificate{*f.cert}
+		}
+		files = func() *tlsFiles { return f }
+	}
+	initial := files()
+
+	// require with a CA is verify-ca, as libpq does
+	if mode == TLSModeRequire && initial.roots != nil {
+		mode = TLSModeVerifyCA
+	}
+	switch mode {
+	case TLSModePrefer, TLSModeRequire:
+		cfg.InsecureSkipVerify = true
+	case TLSModeVerifyCA:
+		// The chain is verified by VerifyConnection without the host name.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyServer(cs, files(), "")
+		}
+	case TLSModeVerifyFull:
+		if !t.Watch {
+			cfg.RootCAs = initial.roots
+			if len(initial.crls) > 0 {
+				cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+					return checkRevocation(cs.VerifiedChains, initial.crls)
+				}
+			}
+			break
+		}
+		// The roots may be reloaded, so the chain and host name are verified by VerifyConnection,
+		// with the server name of each host set by tlsConfigForHost.
+		serverName := t.ServerName
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			name := cmp.Or(cs.ServerName, serverName)
+			if len(name) == 0 {
+				return errors.New("no server name to verify, server_name is required for IP hosts")
+			}
+			return verifyServer(cs, files(), name)
+		}
+	}
+	return cfg, nil
+}
+
+// tlsFiles are the loaded files of TLSCert.
+type tlsFiles struct {
+	cert  *tls.Certificate
+	roots *x509.CertPool
+	crls  []*x509.RevocationList
+}
+
+func (t *TLSCert) loadFiles() (*tlsFiles, error) {
+	f := new(tlsFiles)
+	certBytes, err := readTLSFile("cert_file", t.CertFile)
+	if err != nil {
+		return nil, err
+	}
+	keyBytes, err := readTLSFile("key_file", t.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if (len(certBytes) > 0) != (len(keyBytes) > 0) {
+		return nil, errors.New("cert_file and key_file must be set together")
+	}
+	if len(certBytes) > 0 {
+		cert, err := tls.X509KeyPair(certBytes, keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS keypair: %w", err)
+		}
+		f.cert = &cert
+	}
+
+	caBytes, err := readTLSFile("ca_file", t.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(caBytes) > 0 {
+		f.roots = x509.NewCertPool()
+		if !f.roots.AppendCertsFromPEM(caBytes) {
+			return nil, errors.New("unable to parse CA file")
+		}
+	}
+
+	crlBytes, err := readTLSFile("crl_file", t.CRLFile)
+	if err != nil {
+		return nil, err
+	}
+	if f.crls, err = parseCRLs(crlBytes); err != nil {
+		return nil, err
+	}
+	return f, nil
 }
 
+// tlsConfigForHost sets the server name of cfg to host if it is not set, for SNI and verify-full mode.
+// The server name of IP hosts is not sent by SNI, so it is filled in the state passed to VerifyConnection,
+// which verifies the host name of watched files.
+func tlsConfigForHost(cfg *tls.Config, host string) *tls.Config {
+	if cfg == nil || len(cfg.ServerName) > 0 || len(host) == 0 {
+		return cfg
+	}
+	cfg = cfg.Clone()
+	cfg.ServerName = host
+	if verify := cfg.VerifyConnection; verify != nil {
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			cs.ServerName = cmp.Or(cs.ServerName, host)
+			return verify(cs)
+		}
+	}
+	return cfg
+}
+
+// clientTLS returns the client TLS config and mode of the config, TLSConfig takes precedence over TLSCert,
+// which falls back to the dialect TLS params. The mode of TLSConfig is verify-full, or require if it skips verification,
+// and the mode is empty if none of them is set.
+func clientTLS(c *Config, fromParams func(ConfigParams) *TLSCert) (*tls.Config, string, error) {
+	if c.TLSConfig != nil {
+		if c.TLSConfig.InsecureSkipVerify {
+			return c.TLSConfig, TLSModeRequire, nil
+		}
+		return c.TLSConfig, TLSModeVerifyFull, nil
+	}
+	t := c.TLSCert
+	if t == nil && fromParams != nil {
+		t = fromParams(c.Params)
+	}
+	if t == nil {
+		return nil, "", nil
+	}
+	mode, err := t.TLSMode()
+	if err != nil {
+		return nil, "", err
+	}
+	cfg, err := t.ClientTLSConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid TLS config: %w", err)
+	}
+	return cfg, mode, nil
+}
+
+// verifyServer verifies the server certificates by the roots and CRLs of f, the host name is not verified if dnsName is empty.
+func verifyServer(cs tls.ConnectionState, f *tlsFiles, dnsName string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server has no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	chains, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         f.roots,
+		Intermediates: intermediates,
+		DNSName:       dnsName,
+	})
+	if err != nil {
+		return err
+	}
+	return checkRevocation(chains, f.crls)
+}
+
+// checkRevocation checks the certificates of chains against the CRLs signed by their issuers.
+func checkRevocation(chains [][]*x509.Certificate, crls []*x509.RevocationList) error {
+	for _, chain := range chains {
+		for i, cert := range chain[:max(0, len(chain)-1)] {
+			issuer := chain[i+1]
+			for _, crl := range crls {
+				if !bytes.Equal(crl.RawIssuer, cert.RawIssuer) || crl.CheckSignatureFrom(issuer) != nil {
+					continue
+				}
+				for _, entry := range crl.RevokedCertificateEntries {
+					if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+						return fmt.Errorf("%w: %s", ErrCertificateRevoked, cert.Subject)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func parseCRLs(data []byte) ([]*x509.RevocationList, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var ders [][]byte
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "X509 CRL" {
+			ders = append(ders, block.Bytes)
+		}
+	}
+	if len(ders) == 0 {
+		ders = append(ders, data)
+	}
+
+	crls := make([]*x509.RevocationList, 0, len(ders))
+	for _, der := range ders {
+		crl, err := x509.ParseRevocationList(der)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse CRL file: %w", err)
+		}
+		crls = append(crls, crl)
+	}
+	return crls, nil
+}
+
+// parseTLSVersion parses versions like 1.2, TLS1.2 and TLSv1.2, zero is returned for empty one.
+func parseTLSVersion(v string) (uint16, error) {
+	if len(v) == 0 {
+		return 0, nil
+	}
+	n := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(v), "tls"), "v")
+	if version, ok := tlsVersions[n]; ok {
+		return version, nil
+	}
+	return 0, fmt.Errorf("invalid TLS version %q", v)
+}
+
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[s.Name] = s.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func readTLSFile(field, maybeFile string) ([]byte, error) {
+	if len(maybeFile) == 0 {
+		return nil, nil
+	}
+	data, err := ReadMaybeFile(maybeFile)
+	if err != nil {
+		// The file may be the content, do not report it.
+		return nil, fmt.Errorf("unable to read %s", field)
+	}
+	return data, nil
+}
+
+// CreateBaseTLSConfig creates the TLS config of servers, which requires the certificate and key
+// unless insecureSkipVerify is set, and verifies the client certificates if caFile is set.
 func CreateBaseTLSConfig(caFile string, caOptional bool, certFile, keyFile string, insecureSkipVerify bool) (*tls.Config, error) {
 	certPool := x509.NewCertPool()
 	clientAuth := tls.NoClientCert
@@ -69,15 +442,17 @@ func CreateBaseTLSConfig(caFile string, caOptional bool, certFile, keyFile strin
 	}, nil
 }
 
+// CreateClientTLSConfig creates the client TLS config, the certificate and key are optional.
+// It verifies the server fully unless insecureSkipVerify is set, caOptional is ignored, see TLSCert for other modes.
 func CreateClientTLSConfig(caFile string, caOptional bool, certFile, keyFile string, insecureSkipVerify bool) (*tls.Config, error) {
-	c, err := CreateBaseTLSConfig(
-		caFile,
-		caOptional,
-		certFile,
-		keyFile,
-		insecureSkipVerify,
-	)
-	return c, err
+	t := &TLSCert{
+		CAFile:             caFile,
+		CAOptional:         caOptional,
+		CertFile:           certFile,
+		KeyFile:            keyFile,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+	return t.ClientTLSConfig()
 }
 
 func CreateServerTLSConfig(caFile string, caOptional bool, certFile, keyFile, serverName string, insecureSkipVerify bool) (*tls.Config, error) {
@@ -94,6 +469,8 @@ func CreateServerTLSConfig(caFile string, caOptional bool, certFile, keyFile, se
 	return c, err
 }
 
+// ReadMaybeFile reads the file if maybeFile is an existing path, otherwise maybeFile is the content.
+// Prefer the explicit file: secret reference, see ResolveSecret.
 func ReadMaybeFile(maybeFile string) ([]byte, error) {
 	var data []byte
 	if _, errKey := os.Stat(maybeFile); errKey == nil {
@@ -0,0 +1,573 @@
+package hypersql
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"maps"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/microsoft/go-mssqldb/msdsn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPKI is a CA with a server certificate of localhost and a client certificate,
+// the PEM contents are used as TLSCert files.
+type testPKI struct {
+	caPEM      string
+	serverCert tls.Certificate
+	// crlPEM revokes the server certificate.
+	crlPEM        string
+	clientCertPEM string
+	clientKeyPEM  string
+	clientName    string
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serverTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	serverDER, err := x509.CreateCertificate(rand.Reader, serverTmpl, caCert, &serverKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	crlDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Hour),
+		NextUpdate: time.Now().Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: serverTmpl.SerialNumber, RevocationTime: time.Now()},
+		},
+	}, caCert, caKey)
+	require.NoError(t, err)
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	clientSerial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	clientTmpl := &x509.Certificate{
+		SerialNumber: clientSerial,
+		Subject:      pkix.Name{CommonName: "client " + clientSerial.String()},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTmpl, caCert, &clientKey.PublicKey, caKey)
+	require.NoError(t, err)
+	clientKeyDER, err := x509.MarshalPKCS8PrivateKey(clientKey)
+	require.NoError(t, err)
+
+	return &testPKI{
+		caPEM:         string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})),
+		serverCert:    tls.Certificate{Certificate: [][]byte{serverDER}, PrivateKey: serverKey},
+		crlPEM:        string(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crlDER})),
+		clientCertPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientDER})),
+		clientKeyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: clientKeyDER})),
+		clientName:    clientTmpl.Subject.CommonName,
+	}
+}
+
+// tlsTestServer runs the preamble of the protocol on each connection and then the TLS handshake,
+// the result of handshakes are sent to results.
+type tlsTestServer struct {
+	net.Listener
+	accepted atomic.Int32
+	results  chan tlsTestResult
+}
+
+type tlsTestResult struct {
+	serverName string
+	// clientName is the common name of client certificate.
+	clientName string
+	err        error
+}
+
+func newTLSTestServer(t *testing.T, cfg *tls.Config, preamble func(conn net.Conn) error) *tlsTestServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &tlsTestServer{Listener: ln, results: make(chan tlsTestResult, 16)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.accepted.Add(1)
+			go func() {
+				defer conn.Close()
+				_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+				if preamble != nil {
+					if err := preamble(conn); err != nil {
+						return
+					}
+				}
+				tc := tls.Server(conn, cfg)
+				err := tc.Handshake()
+				r := tlsTestResult{serverName: tc.ConnectionState().ServerName, err: err}
+				if certs := tc.ConnectionState().PeerCertificates; len(certs) > 0 {
+					r.clientName = certs[0].Subject.CommonName
+				}
+				s.results <- r
+			}()
+		}
+	}()
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+func (s *tlsTestServer) port() int {
+	return s.Addr().(*net.TCPAddr).Port
+}
+
+func (s *tlsTestServer) result(t *testing.T) tlsTestResult {
+	select {
+	case r := <-s.results:
+		return r
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no TLS handshake")
+		return tlsTestResult{}
+	}
+}
+
+func TestTLSCert_ClientTLSConfig(t *testing.T) {
+	pki := newTestPKI(t)
+	other := newTestPKI(t)
+	srv := newTLSTestServer(t, &tls.Config{
+		Certificates: []tls.Certificate{pki.serverCert},
+		MaxVersion:   tls.VersionTLS12,
+	}, nil)
+
+	cases := []struct {
+		name string
+		cert *TLSCert
+		host string
+		err  error
+		ok   bool
+	}{
+		{name: "require", cert: &TLSCert{Mode: TLSModeRequire}, ok: true},
+		{name: "require with other CA", cert: &TLSCert{Mode: TLSModeRequire, CAFile: other.caPEM}},
+		{name: "insecure", cert: &TLSCert{InsecureSkipVerify: true}, ok: true},
+		{name: "verify-ca", cert: &TLSCert{Mode: TLSModeVerifyCA, CAFile: pki.caPEM}, host: "127.0.0.1", ok: true},
+		{name: "verify-ca with other CA", cert: &TLSCert{Mode: TLSModeVerifyCA, CAFile: other.caPEM}},
+		{name: "verify-ca revoked", cert: &TLSCert{Mode: TLSModeVerifyCA, CAFile: pki.caPEM, CRLFile: pki.crlPEM}, err: ErrCertificateRevoked},
+		{name: "verify-ca revoked by other CA", cert: &TLSCert{Mode: TLSModeVerifyCA, CAFile: pki.caPEM, CRLFile: other.crlPEM}, ok: true},
+		{name: "verify-full", cert: &TLSCert{CAFile: pki.caPEM}, ok: true},
+		{name: "verify-full server name", cert: &TLSCert{CAFile: pki.caPEM, ServerName: "localhost"}, host: "127.0.0.1", ok: true},
+		{name: "verify-full mismatch", cert: &TLSCert{CAFile: pki.caPEM}, host: "127.0.0.1"},
+		{name: "verify-full revoked", cert: &TLSCert{CAFile: pki.caPEM, CRLFile: pki.crlPEM}, err: ErrCertificateRevoked},
+		{name: "min version", cert: &TLSCert{Mode: TLSModeRequire, MinVersion: "1.3"}},
+		{
+			name: "cipher suites",
+			cert: &TLSCert{CAFile: pki.caPEM, CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}},
+			ok:   true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := tc.cert.ClientTLSConfig()
+			require.NoError(t, err)
+			require.NotNil(t, cfg)
+			assert.Nil(t, cfg.Certificates)
+
+			host := tc.host
+			if len(host) == 0 {
+				host = "localhost"
+			}
+			conn, err := net.Dial("tcp", srv.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+			err = tls.Client(conn, tlsConfigForHost(cfg, host)).Handshake()
+			if tc.ok {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			}
+			_ = srv.result(t)
+		})
+	}
+
+	cfg, err := (&TLSCert{Mode: TLSModeDisable}).ClientTLSConfig()
+	require.NoError(t, err)
+	assert.Nil(t, cfg)
+}
+
+func TestTLSCert_Watch(t *testing.T) {
+	pki := newTestPKI(t)
+	rotated := newTestPKI(t)
+	// The server switches to the rotated certificate with the client.
+	var serverCert atomic.Pointer[tls.Certificate]
+	serverCert.Store(&pki.serverCert)
+	srv := newTLSTestServer(t, &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return serverCert.Load(), nil
+		},
+		ClientAuth: tls.RequireAnyClientCert,
+	}, nil)
+
+	dir := t.TempDir()
+	cert := &TLSCert{
+		CAFile:        filepath.Join(dir, "ca.pem"),
+		CertFile:      filepath.Join(dir, "client.pem"),
+		KeyFile:       filepath.Join(dir, "client.key"),
+		Watch:         true,
+		WatchInterval: time.Nanosecond,
+	}
+	write := func(name, content string, modTime time.Time) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	write("ca.pem", pki.caPEM, time.Now().Add(-time.Minute))
+	write("client.pem", pki.clientCertPEM, time.Now().Add(-time.Minute))
+	write("client.key", pki.clientKeyPEM, time.Now().Add(-time.Minute))
+
+	cfg, err := cert.ClientTLSConfig()
+	require.NoError(t, err)
+	assert.Nil(t, cfg.Certificates)
+	require.NotNil(t, cfg.GetClientCertificate)
+
+	handshake := func(host string) (tlsTestResult, error) {
+		conn, err := net.Dial("tcp", srv.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+		err = tls.Client(conn, tlsConfigForHost(cfg, host)).Handshake()
+		return srv.result(t), err
+	}
+
+	r, err := handshake("localhost")
+	require.NoError(t, err)
+	assert.Equal(t, pki.clientName, r.clientName)
+	// The host name is still verified, with the IP host which is not sent by SNI.
+	_, err = handshake("127.0.0.1")
+	var hostErr x509.HostnameError
+	require.ErrorAs(t, err, &hostErr)
+	assert.Equal(t, "127.0.0.1", hostErr.Host)
+
+	// The key is not rotated yet, the current ones are kept.
+	write("client.pem", rotated.clientCertPEM, time.Now())
+	r, err = handshake("localhost")
+	require.NoError(t, err)
+	assert.Equal(t, pki.clientName, r.clientName)
+
+	write("client.key", rotated.clientKeyPEM, time.Now())
+	write("ca.pem", rotated.caPEM, time.Now())
+	serverCert.Store(&rotated.serverCert)
+	r, err = handshake("localhost")
+	require.NoError(t, err)
+	assert.Equal(t, rotated.clientName, r.clientName)
+
+	// The files are not checked within the interval.
+	w, err := newCertWatcher(&TLSCert{CAFile: cert.CAFile, WatchInterval: time.Hour})
+	require.NoError(t, err)
+	files := w.current()
+	write("ca.pem", pki.caPEM, time.Now().Add(time.Minute))
+	assert.Same(t, files, w.current())
+	w.checked = time.Time{}
+	assert.NotSame(t, files, w.current())
+}
+
+func TestTLSCert_Validate(t *testing.T) {
+	assert.NoError(t, (&TLSCert{}).Validate())
+	assert.NoError(t, (&TLSCert{Mode: "Verify-CA", MinVersion: "TLSv1.3"}).Validate())
+	require.ErrorIs(t, (&TLSCert{Mode: "allow"}).Validate(), ErrInvalidTLSMode)
+	require.ErrorIs(t, (&TLSCert{Mode: TLSModeVerifyFull, InsecureSkipVerify: true}).Validate(), ErrInvalidTLSMode)
+	require.Error(t, (&TLSCert{MinVersion: "1.4"}).Validate())
+	require.Error(t, (&TLSCert{CipherSuites: []string{"TLS_NULL"}}).Validate())
+
+	c := &Config{Dialect: DialectPostgres, Host: "localhost", TLSCert: &TLSCert{Mode: "strict"}}
+	var ce *ConfigError
+	require.ErrorAs(t, c.Validate(context.Background()), &ce)
+	assert.Equal(t, "tls_cert", ce.Path)
+
+	_, err := CreateClientTLSConfig("", false, "", "", false)
+	require.NoError(t, err)
+}
+
+func TestTLSCertFromParams(t *testing.T) {
+	assert.Nil(t, postgresTLSCertFromParams(ConfigParams{"application_name": "app"}))
+	assert.Equal(t, &TLSCert{Mode: TLSModePrefer, CAFile: "/ca.pem"},
+		postgresTLSCertFromParams(ConfigParams{"sslrootcert": "/ca.pem"}))
+	assert.Equal(t, &TLSCert{Mode: TLSModeVerifyCA, CRLFile: "/crl.pem"},
+		postgresTLSCertFromParams(ConfigParams{"sslmode": "verify-ca", "sslcrl": "/crl.pem"}))
+
+	assert.Nil(t, mysqlTLSCertFromParams(ConfigParams{"collation": "utf8mb4_bin"}))
+	assert.Equal(t, &TLSCert{Mode: TLSModeVerifyFull, CAFile: "/ca.pem", MinVersion: "TLSv1.2"},
+		mysqlTLSCertFromParams(ConfigParams{"ssl-mode": "VERIFY_IDENTITY", "ssl-ca": "/ca.pem", "tls-version": "TLSv1.3, TLSv1.2"}))
+
+	assert.Nil(t, sqlServerTLSCertFromParams(ConfigParams{"app name": "app"}))
+	assert.Equal(t, &TLSCert{Mode: TLSModeVerifyFull, CAFile: "/ca.pem", ServerName: "db.test", MinVersion: "1.2"},
+		sqlServerTLSCertFromParams(ConfigParams{"encrypt": "true", "certificate": "/ca.pem", "hostnameincertificate": "db.test", "tlsmin": "1.2"}))
+	assert.Equal(t, TLSModeRequire, sqlServerTLSCertFromParams(ConfigParams{"trustservercertificate": "true"}).Mode)
+	assert.Equal(t, TLSModeDisable, sqlServerTLSCertFromParams(ConfigParams{"encrypt": "DISABLE"}).Mode)
+}
+
+// postgresSSLPreamble answers the SSLRequest with S, or N if refused.
+func postgresSSLPreamble(refused bool) func(conn net.Conn) error {
+	return func(conn net.Conn) error {
+		buf := make([]byte, 8)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return err
+		}
+		if refused {
+			_, _ = conn.Write([]byte("N"))
+			return io.EOF
+		}
+		_, err := conn.Write([]byte("S"))
+		return err
+	}
+}
+
+// mysqlSSLPreamble sends the initial handshake with CLIENT_SSL and reads the SSL request of client.
+func mysqlSSLPreamble(conn net.Conn) error {
+	const capabilities = 0x1 | 0x200 | 0x800 | 0x8000 | 0x80000
+	payload := []byte{10}
+	payload = append(payload, "8.0.36\x00"...)
+	payload = append(payload, 1, 0, 0, 0)
+	payload = append(payload, "abcdefgh"...)
+	payload = append(payload, 0)
+	payload = binary.LittleEndian.AppendUint16(payload, capabilities&0xffff)
+	payload = append(payload, 45)
+	payload = binary.LittleEndian.AppendUint16(payload, 2)
+	payload = binary.LittleEndian.AppendUint16(payload, capabilities>>16)
+	payload = append(payload, 21)
+	payload = append(payload, make([]byte, 10)...)
+	payload = append(payload, "ijklmnopqrst\x00"...)
+	payload = append(payload, "mysql_native_password\x00"...)
+
+	header := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), 0}
+	if _, err := conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	n := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	_, err := io.ReadFull(conn, make([]byte, n))
+	return err
+}
+
+func TestDialects_TLS(t *testing.T) {
+	pki := newTestPKI(t)
+	other := newTestPKI(t)
+	serverTLS := &tls.Config{Certificates: []tls.Certificate{pki.serverCert}}
+
+	cases := []struct {
+		dialect  string
+		preamble func(conn net.Conn) error
+		params   ConfigParams
+	}{
+		{dialect: DialectPostgres, preamble: postgresSSLPreamble(false)},
+		{dialect: DialectPostgres, preamble: postgresSSLPreamble(false), params: ConfigParams{"sslmode": "verify-full"}},
+		{dialect: DialectMySQL, preamble: mysqlSSLPreamble},
+		{dialect: DialectMySQL, preamble: mysqlSSLPreamble, params: ConfigParams{"ssl-mode": "VERIFY_IDENTITY"}},
+	}
+	for _, tc := range cases {
+		connect := func(t *testing.T, srv *tlsTestServer, cert *TLSCert) {
+			c := &Config{
+				Dialect:     tc.dialect,
+				Transport:   "tcp",
+				Name:        "test",
+				User:        "test",
+				DialTimeout: time.Second,
+				Hosts:       []HostPort{{Host: "localhost", Port: srv.port()}},
+				Params:      ConfigParams{},
+				TLSCert:     cert,
+			}
+			if tc.params != nil {
+				c.Params = maps.Clone(tc.params)
+				c.Params[map[string]string{DialectPostgres: "sslrootcert", DialectMySQL: "ssl-ca"}[tc.dialect]] = cert.CAFile
+				c.TLSCert = nil
+			}
+			connector, err := connectors[tc.dialect](context.Background(), c)
+			require.NoError(t, err)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			// The server closes the connection after the handshake.
+			_, err = connector.Connect(ctx)
+			require.Error(t, err)
+		}
+
+		t.Run(tc.dialect+"/verify-full", func(t *testing.T) {
+			srv := newTLSTestServer(t, serverTLS, tc.preamble)
+			connect(t, srv, &TLSCert{CAFile: pki.caPEM})
+			r := srv.result(t)
+			require.NoError(t, r.err)
+			// The host is the server name.
+			assert.Equal(t, "localhost", r.serverName)
+		})
+
+		t.Run(tc.dialect+"/unknown CA", func(t *testing.T) {
+			srv := newTLSTestServer(t, serverTLS, tc.preamble)
+			connect(t, srv, &TLSCert{CAFile: other.caPEM})
+			require.Error(t, srv.result(t).err)
+		})
+	}
+
+	// The TLS connection is refused, and then it connects without TLS in prefer mode.
+	for mode, accepted := range map[string]int32{TLSModePrefer: 2, TLSModeRequire: 1} {
+		t.Run("postgres/"+mode, func(t *testing.T) {
+			srv := newTLSTestServer(t, serverTLS, postgresSSLPreamble(true))
+			c := &Config{
+				Dialect:     DialectPostgres,
+				Name:        "test",
+				User:        "test",
+				DialTimeout: time.Second,
+				Hosts:       []HostPort{{Host: "127.0.0.1", Port: srv.port()}},
+				TLSCert:     &TLSCert{Mode: mode},
+			}
+			connector, err := GetPostgresConnector(context.Background(), c)
+			require.NoError(t, err)
+			_, err = connector.Connect(context.Background())
+			require.Error(t, err)
+			assert.Equal(t, accepted, srv.accepted.Load())
+		})
+	}
+}
+
+func TestMySQL_TLSRegistry(t *testing.T) {
+	pkis := []*testPKI{newTestPKI(t), newTestPKI(t)}
+	servers := make([]*tlsTestServer, len(pkis))
+	connectors := make([]*mysqlTLSConnector, len(pkis))
+	for i, pki := range pkis {
+		servers[i] = newTLSTestServer(t, &tls.Config{Certificates: []tls.Certificate{pki.serverCert}}, mysqlSSLPreamble)
+		// The same database on different servers.
+		c := &Config{
+			Dialect:     DialectMySQL,
+			Transport:   "tcp",
+			Name:        "app",
+			DialTimeout: time.Second,
+			Hosts:       []HostPort{{Host: "localhost", Port: servers[i].port()}},
+			TLSCert:     &TLSCert{CAFile: pki.caPEM},
+		}
+		connector, err := GetMySQLConnector(context.Background(), c)
+		require.NoError(t, err)
+		connectors[i] = connector.(*mysqlTLSConnector)
+		require.Len(t, connectors[i].keys, 1)
+	}
+	assert.NotEqual(t, connectors[0].keys, connectors[1].keys)
+
+	var wg sync.WaitGroup
+	for _, connector := range connectors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = connector.Connect(context.Background())
+		}()
+	}
+	wg.Wait()
+	for _, srv := range servers {
+		require.NoError(t, srv.result(t).err)
+	}
+
+	keys := connectors[0].keys
+	dsn := "tcp(localhost:3306)/app?tls=" + keys[0]
+	_, err := mysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	// The DB closes the connector.
+	require.NoError(t, sql.OpenDB(connectors[0]).Close())
+	_, err = mysql.ParseDSN(dsn)
+	require.Error(t, err)
+	require.NoError(t, connectors[1].Close())
+}
+
+func TestDialects_TLSConfig(t *testing.T) {
+	c := &Config{Dialect: DialectMySQL, Transport: "tcp", Name: "test", Host: "localhost", Port: 3306}
+	c.TLSCert = &TLSCert{Mode: TLSModePrefer}
+	cc, err := ToMySQLConfig(c)
+	require.NoError(t, err)
+	assert.NotNil(t, cc.TLS)
+	assert.Empty(t, cc.TLSConfig)
+	assert.True(t, cc.AllowFallbackToPlaintext)
+
+	ctx := context.Background()
+	_, err = ToMySQLDSN(ctx, c)
+	require.ErrorIs(t, err, ErrMySQLTLSDSN)
+	dsn, deregister, err := ToMySQLTLSDSN(ctx, c)
+	require.NoError(t, err)
+	pc, err := mysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	assert.NotNil(t, pc.TLS)
+	deregister()
+	_, err = mysql.ParseDSN(dsn)
+	require.Error(t, err)
+
+	c.TLSCert = nil
+	cc, err = ToMySQLConfig(c)
+	require.NoError(t, err)
+	assert.Empty(t, cc.TLSConfig)
+
+	c = &Config{Dialect: DialectSQLServer, Name: "test", Host: "localhost", Port: 1433}
+	mc, err := ToSQLServerConfig(c)
+	require.NoError(t, err)
+	assert.Nil(t, mc.TLSConfig)
+	assert.EqualValues(t, msdsn.EncryptionOff, mc.Encryption)
+
+	c.TLSCert = &TLSCert{Mode: TLSModeVerifyFull}
+	mc, err = ToSQLServerConfig(c)
+	require.NoError(t, err)
+	require.NotNil(t, mc.TLSConfig)
+	assert.True(t, mc.TLSConfig.DynamicRecordSizingDisabled)
+	assert.EqualValues(t, msdsn.EncryptionRequired, mc.Encryption)
+
+	c.Params = ConfigParams{"encrypt": "strict"}
+	mc, err = ToSQLServerConfig(c)
+	require.NoError(t, err)
+	assert.EqualValues(t, msdsn.EncryptionStrict, mc.Encryption)
+
+	c.TLSCert = &TLSCert{Mode: TLSModeDisable}
+	c.Params = nil
+	mc, err = ToSQLServerConfig(c)
+	require.NoError(t, err)
+	assert.Nil(t, mc.TLSConfig)
+	assert.EqualValues(t, msdsn.EncryptionDisabled, mc.Encryption)
+
+	c.TLSCert = &TLSCert{CAFile: "not a certificate"}
+	_, err = ToSQLServerConfig(c)
+	require.Error(t, err)
+	_, err = GetPostgresConnector(context.Background(), &Config{Dialect: DialectPostgres, Host: "localhost", TLSCert: c.TLSCert})
+	require.Error(t, err)
+}
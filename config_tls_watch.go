@@ -0,0 +1,91 @@
+package hypersql
+
+import (
+	"crypto/tls"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultCertWatchInterval is the minimum interval of checking the watched TLS files when it is not set.
+const DefaultCertWatchInterval = 10 * time.Second
+
+// certWatcher reloads the files of TLSCert when their modification time or size is changed.
+// The files are checked on handshakes at most once per interval, so no goroutine is needed.
+// The current files are kept if the changed ones fail to load, e.g. the key is not written yet.
+type certWatcher struct {
+	t        TLSCert
+	interval time.Duration
+
+	mu      sync.Mutex
+	checked time.Time
+	stamps  []fileStamp
+	files   *tlsFiles
+}
+
+// fileStamp is the modification time and size of a file, it is zero for contents.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func newCertWatcher(t *TLSCert) (*certWatcher, error) {
+	w := &certWatcher{
+		t:        *t,
+		interval: t.WatchInterval,
+	}
+	if w.interval <= 0 {
+		w.interval = DefaultCertWatchInterval
+	}
+	w.stamps = w.stat()
+	files, err := w.t.loadFiles()
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+	w.checked = time.Now()
+	return w, nil
+}
+
+// current returns the current files, which are reloaded if they are changed.
+func (w *certWatcher) current() *tlsFiles {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	if now.Sub(w.checked) < w.interval {
+		return w.files
+	}
+	w.checked = now
+	stamps := w.stat()
+	if slices.Equal(stamps, w.stamps) {
+		return w.files
+	}
+	if files, err := w.t.loadFiles(); err == nil {
+		w.files, w.stamps = files, stamps
+	}
+	return w.files
+}
+
+// clientCertificate serves the current certificate by tls.Config.GetClientCertificate.
+func (w *certWatcher) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if cert := w.current().cert; cert != nil {
+		return cert, nil
+	}
+	// No certificate is sent.
+	return new(tls.Certificate), nil
+}
+
+func (w *certWatcher) stat() []fileStamp {
+	paths := []string{w.t.CertFile, w.t.KeyFile, w.t.CAFile, w.t.CRLFile}
+	stamps := make([]fileStamp, len(paths))
+	for i, path := range paths {
+		if len(path) == 0 {
+			continue
+		}
+		if fi, err := os.Stat(path); err == nil {
+			stamps[i] = fileStamp{modTime: fi.ModTime(), size: fi.Size()}
+		}
+	}
+	return stamps
+}
@@ -1,13 +1,25 @@
 package hypersql
 
 import (
+	"fmt"
 	"maps"
+	"net"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cast"
 	"github.com/xo/dburl"
 )
 
+// ParseURL parses the database URL to Config.
+// Multiple hosts are separated by comma, e.g. postgres://h1:5432,h2:5432/db,
+// they are set to Hosts and the first one is set to Host and Port too.
 func ParseURL(url string) (*Config, error) {
+	url, hosts, err := splitURLHosts(url)
+	if err != nil {
+		return nil, err
+	}
+
 	uu, err := dburl.Parse(url)
 	if err != nil {
 		return nil, err
@@ -18,12 +30,19 @@ func ParseURL(url string) (*Config, error) {
 		return nil, ErrUnsupportedDialect
 	}
 
+	// The path of SQLite is the file, the one of servers is the database name after the slash.
+	name := uu.EscapedPath()
+	if dialect != DialectSQLite {
+		name = strings.TrimPrefix(name, "/")
+	}
+
 	c := &Config{
 		Transport: uu.Transport,
 		Dialect:   dialect,
-		Name:      uu.EscapedPath(),
-		Host:      uu.Host,
-		Port:      cast.ToInt(uu.Port),
+		Name:      name,
+		Host:      uu.Hostname(),
+		Port:      cast.ToInt(uu.Port()),
+		Hosts:     hosts,
 		Params:    make(ConfigParams),
 	}
 
@@ -40,3 +59,52 @@ func ParseURL(url string) (*Config, error) {
 
 	return c, nil
 }
+
+// splitURLHosts replaces the comma separated hosts of the URL with the first one,
+// because url.Parse only accepts one host.
+func splitURLHosts(url string) (string, []HostPort, error) {
+	_, rest, ok := strings.Cut(url, "://")
+	if !ok {
+		return url, nil, nil
+	}
+	authority := rest
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		authority = rest[:i]
+	}
+	hostsPart := authority
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		hostsPart = authority[i+1:]
+	}
+	if !strings.Contains(hostsPart, ",") {
+		return url, nil, nil
+	}
+
+	var hosts []HostPort
+	for _, hp := range strings.Split(hostsPart, ",") {
+		h, err := parseHostPort(hp)
+		if err != nil {
+			return "", nil, err
+		}
+		hosts = append(hosts, h)
+	}
+
+	start := len(url) - len(rest) + len(authority) - len(hostsPart)
+	first := strings.Split(hostsPart, ",")[0]
+	return url[:start] + first + url[start+len(hostsPart):], hosts, nil
+}
+
+func parseHostPort(hp string) (HostPort, error) {
+	if len(hp) == 0 {
+		return HostPort{}, fmt.Errorf("empty host in URL")
+	}
+	host, port, err := net.SplitHostPort(hp)
+	if err != nil {
+		// No port
+		return HostPort{Host: strings.Trim(hp, "[]")}, nil
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p <= 0 || p > 65535 {
+		return HostPort{}, fmt.Errorf("invalid port %q of host %q in URL", port, host)
+	}
+	return HostPort{Host: host, Port: p}, nil
+}
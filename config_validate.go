@@ -0,0 +1,172 @@
+package hypersql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrRequiredField = errors.New("field is required")
+
+	ErrUnknownParam = errors.New("unknown param")
+
+	ErrConflictingTLS = errors.New("conflicting TLS sources")
+
+	// knownParams holds the checker of known Params keys for each dialect.
+	knownParams = make(map[string]func(key string) bool)
+
+	// tlsParams holds the Params keys which are TLS sources for each dialect,
+	// they conflict with TLSConfig and TLSCert.
+	tlsParams = make(map[string][]string)
+)
+
+// RegisterKnownParams registers the checker of known Params keys of the dialect, Validate reports the unknown ones.
+func RegisterKnownParams(dialect string, known func(key string) bool) {
+	knownParams[dialect] = known
+}
+
+// Validate checks the config and returns all the violations joined, each of them is a *ConfigError with the field path.
+// The Extra is validated too if it implements Validator.
+// The unknown params are reported with ErrUnknownParam, but they are only warnings when the DB is opened,
+// since the known params of a dialect may miss some of its driver.
+func (c *Config) Validate(ctx context.Context) error {
+	if c == nil {
+		return ErrNilConfig
+	}
+	return errors.Join(c.validate(ctx)...)
+}
+
+// validateForOpen is Validate, except that the unknown params are logged by Config.Logger as warnings.
+func (c *Config) validateForOpen(ctx context.Context) error {
+	var errs []error
+	for _, err := range c.validate(ctx) {
+		if !errors.Is(err, ErrUnknownParam) {
+			errs = append(errs, err)
+		} else if l := c.Logger; l != nil {
+			l("%v, it is passed to the driver as is", err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Config) validate(ctx context.Context) []error {
+	var errs []error
+	invalid := func(path string, err error) {
+		errs = append(errs, &ConfigError{Path: path, Err: err})
+	}
+
+	dialect := GetFormalDialect(c.Dialect)
+	if GetConnector(dialect) == nil {
+		invalid("dialect", fmt.Errorf("%w: %q", ErrUnsupportedDialect, c.Dialect))
+	}
+
+	// Required fields
+	switch dialect {
+	case "":
+	case DialectSQLite, DialectSQLite3:
+		if len(c.Name) == 0 {
+			invalid("name", ErrRequiredField)
+		}
+	default:
+		if len(c.Hosts) == 0 && len(c.Host) == 0 {
+			invalid("host", ErrRequiredField)
+		}
+	}
+
+	// Hosts and ports
+	if !isValidPort(c.Port) {
+		invalid("port", fmt.Errorf("%d is out of range 0-65535", c.Port))
+	}
+	for i, h := range c.Hosts {
+		if len(h.Host) == 0 {
+			invalid(fmt.Sprintf("hosts[%d].host", i), ErrRequiredField)
+		}
+		if !isValidPort(h.Port) {
+			invalid(fmt.Sprintf("hosts[%d].port", i), fmt.Errorf("%d is out of range 0-65535", h.Port))
+		}
+	}
+
+	// Pool
+	if c.MaxOpenConns > 0 {
+		if c.MaxIdleConns > c.MaxOpenConns {
+			invalid("max_idle_conns", fmt.Errorf("%d exceeds max_open_conns %d", c.MaxIdleConns, c.MaxOpenConns))
+		}
+		if c.MinIdleConns > c.MaxOpenConns {
+			invalid("min_idle_conns", fmt.Errorf("%d exceeds max_open_conns %d", c.MinIdleConns, c.MaxOpenConns))
+		}
+	}
+	if c.MaxIdleConns > 0 && c.MinIdleConns > c.MaxIdleConns {
+		invalid("min_idle_conns", fmt.Errorf("%d exceeds max_idle_conns %d", c.MinIdleConns, c.MaxIdleConns))
+	}
+	for path, n := range map[string]int{
+		"min_idle_conns":    c.MinIdleConns,
+		"warm_up_conns":     c.WarmUpConns,
+		"expected_replicas": c.ExpectedReplicas,
+	} {
+		if n < 0 {
+			invalid(path, fmt.Errorf("%d is negative", n))
+		}
+	}
+	if c.DialTimeout < 0 {
+		invalid("dial_timeout", fmt.Errorf("%s is negative", c.DialTimeout))
+	}
+
+	// TLS
+	if c.TLSConfig != nil && c.TLSCert != nil {
+		invalid("tls_cert", fmt.Errorf("%w: both TLSConfig and tls_cert are set", ErrConflictingTLS))
+	}
+	if c.TLSCert != nil {
+		if err := c.TLSCert.Validate(); err != nil {
+			invalid("tls_cert", err)
+		}
+	}
+	if c.TLSConfig != nil || c.TLSCert != nil {
+		for _, k := range tlsParams[dialect] {
+			if _, ok := c.Params[k]; ok {
+				invalid("params."+k, fmt.Errorf("%w: TLS is set by TLSConfig or tls_cert", ErrConflictingTLS))
+			}
+		}
+	}
+
+	// Params
+	if known, ok := knownParams[dialect]; ok {
+		for _, k := range sortedParamKeys(c.Params) {
+			if !strings.HasPrefix(k, "x-") && !known(k) {
+				invalid("params."+k, ErrUnknownParam)
+			}
+		}
+	}
+
+	// Extra
+	if c.Extra != nil {
+		if t, ok := extraTypes[dialect]; ok && reflect.TypeOf(c.Extra) != reflect.PointerTo(t) {
+			invalid("extra", fmt.Errorf("expected *%s, got %T", t.Name(), c.Extra))
+		} else if v, ok := c.Extra.(Validator); ok {
+			if err := v.Validate(ctx); err != nil {
+				invalid("extra", err)
+			}
+		}
+	}
+
+	slices.SortStableFunc(errs, func(a, b error) int {
+		return strings.Compare(a.(*ConfigError).Path, b.(*ConfigError).Path)
+	})
+	return errs
+}
+
+func isValidPort(port int) bool {
+	return port >= 0 && port <= 65535
+}
+
+func sortedParamKeys(p ConfigParams) []string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
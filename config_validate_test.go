@@ -0,0 +1,141 @@
+package hypersql
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	ctx := context.Background()
+	valid := &Config{
+		Dialect:      DialectPostgres,
+		Host:         "localhost",
+		Port:         5432,
+		MaxOpenConns: 10,
+		MaxIdleConns: 5,
+		Params:       ConfigParams{"sslmode": "disable", "TimeZone": "UTC", XParamDialTimeout: "5s"},
+		Extra:        &PostgresExtra{StatementCacheCapacity: 64},
+	}
+	require.NoError(t, valid.Validate(ctx))
+
+	cases := []struct {
+		name  string
+		alter func(c *Config)
+		paths []string
+		err   error
+	}{
+		{name: "dialect", alter: func(c *Config) { c.Dialect = "oracle" }, paths: []string{"dialect"}, err: ErrUnsupportedDialect},
+		{name: "host", alter: func(c *Config) { c.Host = "" }, paths: []string{"host"}, err: ErrRequiredField},
+		{name: "port", alter: func(c *Config) { c.Port = 70000 }, paths: []string{"port"}},
+		{
+			name:  "hosts",
+			alter: func(c *Config) { c.Hosts = []HostPort{{Host: "h1", Port: 5432}, {Port: -1}} },
+			paths: []string{"hosts[1].host", "hosts[1].port"},
+		},
+		{name: "max_idle_conns", alter: func(c *Config) { c.MaxIdleConns = 20 }, paths: []string{"max_idle_conns"}},
+		{name: "min_idle_conns", alter: func(c *Config) { c.MinIdleConns = 8 }, paths: []string{"min_idle_conns"}},
+		{
+			name: "tls",
+			alter: func(c *Config) {
+				c.TLSConfig = &tls.Config{}
+				c.TLSCert = &TLSCert{}
+				c.Params["sslrootcert"] = "/ca.pem"
+			},
+			paths: []string{"params.sslrootcert", "tls_cert"},
+			err:   ErrConflictingTLS,
+		},
+		{name: "params", alter: func(c *Config) { c.Params["sslmdoe"] = "disable" }, paths: []string{"params.sslmdoe"}, err: ErrUnknownParam},
+		{name: "extra type", alter: func(c *Config) { c.Extra = &MySQLExtra{} }, paths: []string{"extra"}},
+		{name: "extra", alter: func(c *Config) { c.Extra = &PostgresExtra{StatementCacheCapacity: -1} }, paths: []string{"extra"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := *valid
+			c.Params = ConfigParams{"sslmode": "disable"}
+			tc.alter(&c)
+
+			err := c.Validate(ctx)
+			require.Error(t, err)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+			}
+			assert.Equal(t, tc.paths, configErrorPaths(err))
+		})
+	}
+}
+
+func TestConfig_Validate_UnknownParamsOpen(t *testing.T) {
+	cases := []struct {
+		dialect string
+		params  ConfigParams
+	}{
+		{dialect: DialectMySQL, params: ConfigParams{"charset": "utf8mb4"}},
+		{dialect: DialectPostgres, params: ConfigParams{"sslmode": "disable", "search_path": "app", "statement_timeout": "5000"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.dialect, func(t *testing.T) {
+			var warnings []string
+			c := &Config{
+				Dialect:     tc.dialect,
+				Transport:   "tcp",
+				Name:        "test",
+				Hosts:       []HostPort{refusedHostPort(t)},
+				DialTimeout: time.Second,
+				Params:      tc.params,
+				Logger: func(format string, args ...any) {
+					warnings = append(warnings, fmt.Sprintf(format, args...))
+				},
+			}
+			// The open fails to connect rather than by the params, which are logged if they are not known.
+			_, err := NewSqlDB(c)
+			require.Error(t, err)
+			var ce *ConfigError
+			assert.False(t, errors.As(err, &ce), "%v", err)
+			require.NotEmpty(t, warnings)
+			for _, w := range warnings {
+				assert.Contains(t, w, ErrUnknownParam.Error())
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_Aggregated(t *testing.T) {
+	c := &Config{
+		Dialect:      DialectMySQL,
+		Port:         -1,
+		MaxOpenConns: 1,
+		MaxIdleConns: 2,
+		Params:       ConfigParams{"unknown": "1"},
+	}
+	err := c.Validate(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, []string{"host", "max_idle_conns", "params.unknown", "port"}, configErrorPaths(err))
+}
+
+func TestNewSqlDB_Validate(t *testing.T) {
+	_, err := NewSqlDB(&Config{Dialect: DialectPostgres, Port: 99999})
+	var ce *ConfigError
+	require.ErrorAs(t, err, &ce)
+}
+
+func configErrorPaths(err error) []string {
+	var paths []string
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		return nil
+	}
+	for _, e := range joined.Unwrap() {
+		var ce *ConfigError
+		if errors.As(e, &ce) {
+			paths = append(paths, ce.Path)
+		}
+	}
+	return paths
+}
@@ -3,6 +3,9 @@ package hypersql
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand/v2"
 )
 
 var _ driver.Connector = (*dsnConnector)(nil)
@@ -37,3 +40,48 @@ var _ driver.Connector = (*wrapConnector)(nil)
 func WrapConnector(c driver.Connector) driver.Connector {
 	return &wrapConnector{c: c}
 }
+
+var _ driver.Connector = (*failoverConnector)(nil)
+
+// failoverConnector tries the connectors of hosts in order or shuffled until one connects.
+type failoverConnector struct {
+	connectors []driver.Connector
+	shuffle    bool
+}
+
+func newFailoverConnector(connectors []driver.Connector, shuffle bool) driver.Connector {
+	if len(connectors) == 1 {
+		return connectors[0]
+	}
+	return &failoverConnector{connectors: connectors, shuffle: shuffle}
+}
+
+func (c *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	order := make([]int, len(c.connectors))
+	for i := range order {
+		order[i] = i
+	}
+	if c.shuffle {
+		rand.Shuffle(len(order), func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+	}
+
+	var errs []error
+	for _, i := range order {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		conn, err := c.connectors[i].Connect(ctx)
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("unable to connect to any host: %w", errors.Join(errs...))
+}
+
+func (c *failoverConnector) Driver() driver.Driver {
+	return c.connectors[0].Driver()
+}
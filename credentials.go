@@ -0,0 +1,185 @@
+package hypersql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCredentialExpiryMargin is the longest time before expiry that cached credentials are refreshed.
+const maxCredentialExpiryMargin = 30 * time.Second
+
+type (
+	// CredentialProvider provides the credentials when each new physical connection is made.
+	// An empty user means Config.User is used, a zero expiry means the credentials are not cached.
+	CredentialProvider interface {
+		Credentials(ctx context.Context) (user, password string, expiry time.Time, err error)
+	}
+
+	CredentialProviderFunc func(ctx context.Context) (user, password string, expiry time.Time, err error)
+
+	cachedCredentialProvider struct {
+		p CredentialProvider
+
+		mu        sync.Mutex
+		user      string
+		password  string
+		expiry    time.Time
+		refreshAt time.Time
+		cached    bool
+	}
+
+	// credentialDocument is the JSON output of file and exec providers.
+	credentialDocument struct {
+		Username string    `json:"username"`
+		Password string    `json:"password"`
+		Token    string    `json:"token"`
+		Expiry   time.Time `json:"expiry"`
+	}
+
+	// connectorDriver adapts a driver.Connector to driver.Driver,
+	// so the connectors with BeforeConnect callbacks can be wrapped by DriverWrappers and DriverHooks.
+	connectorDriver struct {
+		c driver.Connector
+	}
+)
+
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (string, string, time.Time, error) {
+	return f(ctx)
+}
+
+// CachedCredentials caches the credentials of p until they are about to expire.
+// The credentials without expiry are not cached, so the rotated ones are picked up by the next connection.
+func CachedCredentials(p CredentialProvider) CredentialProvider {
+	if cp, ok := p.(*cachedCredentialProvider); ok {
+		return cp
+	}
+	return &cachedCredentialProvider{p: p}
+}
+
+func (p *cachedCredentialProvider) Credentials(ctx context.Context) (string, string, time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cached && time.Now().Before(p.refreshAt) {
+		return p.user, p.password, p.expiry, nil
+	}
+
+	user, password, expiry, err := p.p.Credentials(ctx)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	p.user, p.password, p.expiry, p.cached = user, password, expiry, !expiry.IsZero()
+	if p.cached {
+		// Refresh a little earlier, so the credentials do not expire while connecting.
+		margin := min(maxCredentialExpiryMargin, time.Until(expiry)/10)
+		p.refreshAt = expiry.Add(-margin)
+	}
+	return user, password, expiry, nil
+}
+
+// FileCredentials reads the credentials from the file on every call, e.g. a token rendered by Vault agent.
+// The file contains either the password only, or a JSON object with username, password or token, and expiry.
+// ttl is the expiry of credentials without expiry, zero means they are read again for each connection.
+func FileCredentials(path string, ttl time.Duration) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context) (string, string, time.Time, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", time.Time{}, fmt.Errorf("unable to read credentials file: %w", err)
+		}
+		return parseCredentials(data, ttl)
+	})
+}
+
+// EnvCredentials reads the user and password from the environment variables on every call,
+// they have no expiry so they are not cached.
+// userKey is optional, the password variable must be set.
+func EnvCredentials(userKey, passwordKey string) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context) (string, string, time.Time, error) {
+		password, ok := os.LookupEnv(passwordKey)
+		if !ok {
+			return "", "", time.Time{}, fmt.Errorf("environment variable %s is not set", passwordKey)
+		}
+		var user string
+		if len(userKey) > 0 {
+			user = os.Getenv(userKey)
+		}
+		return user, password, time.Time{}, nil
+	})
+}
+
+// ExecCredentials runs the command and reads the credentials from its output, e.g. `aws rds generate-db-auth-token`.
+// The output is parsed as FileCredentials does, ttl is the expiry of credentials without expiry.
+func ExecCredentials(ttl time.Duration, name string, args ...string) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context) (string, string, time.Time, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", "", time.Time{}, fmt.Errorf("unable to run credentials command %s: %w: %s",
+				name, err, strings.TrimSpace(stderr.String()))
+		}
+		return parseCredentials(stdout.Bytes(), ttl)
+	})
+}
+
+func parseCredentials(data []byte, ttl time.Duration) (string, string, time.Time, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return "", "", time.Time{}, fmt.Errorf("empty credentials")
+	}
+
+	var doc credentialDocument
+	if data[0] == '{' {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			// Do not wrap the error, it may contain the secret.
+			return "", "", time.Time{}, fmt.Errorf("invalid credentials JSON")
+		}
+		if len(doc.Password) == 0 {
+			doc.Password = doc.Token
+		}
+	} else {
+		doc.Password = string(data)
+	}
+
+	if doc.Expiry.IsZero() && ttl > 0 {
+		doc.Expiry = time.Now().Add(ttl)
+	}
+	return doc.Username, doc.Password, doc.Expiry, nil
+}
+
+// credentialsFor returns the cached credential provider of config, or nil if it is not set.
+func credentialsFor(c *Config) CredentialProvider {
+	if c.CredentialProvider == nil {
+		return nil
+	}
+	return CachedCredentials(c.CredentialProvider)
+}
+
+// newContextConnector wraps c by the wrappers and hooks, the context of Connect is passed to c
+// through the wrappers implementing driver.DriverContext, see wrapper.WrapDriver.
+// The wrappers which do not implement it open c without the context.
+func newContextConnector(c driver.Connector, wrappers DriverWrappers, hooks DriverHooks) driver.Connector {
+	drv := WrapDriver(connectorDriver{c: c}, wrappers, hooks)
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if wc, err := dc.OpenConnector(""); err == nil {
+			return wc
+		}
+	}
+	return &dsnConnector{drv: drv}
+}
+
+func (d connectorDriver) Open(_ string) (driver.Conn, error) {
+	return d.c.Connect(context.Background())
+}
+
+func (d connectorDriver) OpenConnector(_ string) (driver.Connector, error) {
+	return d.c, nil
+}
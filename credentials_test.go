@@ -0,0 +1,208 @@
+package hypersql
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedCredentials(t *testing.T) {
+	var calls atomic.Int32
+	var expiry time.Time
+	p := CachedCredentials(CredentialProviderFunc(func(ctx context.Context) (string, string, time.Time, error) {
+		calls.Add(1)
+		return "user", "token", expiry, nil
+	}))
+	assert.Same(t, p, CachedCredentials(p))
+
+	ctx := context.Background()
+	// Without expiry the credentials are not cached.
+	for range 3 {
+		user, password, _, err := p.Credentials(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "token", password)
+	}
+	assert.Equal(t, int32(3), calls.Load())
+
+	// The credentials are refreshed when they are about to expire.
+	p = CachedCredentials(CredentialProviderFunc(func(ctx context.Context) (string, string, time.Time, error) {
+		calls.Add(1)
+		return "", "token", time.Now().Add(time.Millisecond), nil
+	}))
+	calls.Store(0)
+	_, _, _, err := p.Credentials(ctx)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, _, _, err = p.Credentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// The failures are not cached.
+	failure := errors.New("failure")
+	p = CachedCredentials(CredentialProviderFunc(func(ctx context.Context) (string, string, time.Time, error) {
+		calls.Add(1)
+		return "", "", time.Time{}, failure
+	}))
+	calls.Store(0)
+	for range 2 {
+		_, _, _, err = p.Credentials(ctx)
+		require.ErrorIs(t, err, failure)
+	}
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestFileCredentials(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(path, []byte("secret\n"), 0o600))
+	user, password, expiry, err := FileCredentials(path, 0).Credentials(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, user)
+	assert.Equal(t, "secret", password)
+	assert.True(t, expiry.IsZero())
+
+	_, _, expiry, err = FileCredentials(path, time.Minute).Credentials(ctx)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expiry, time.Second)
+
+	at := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	path = filepath.Join(dir, "credentials.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"username":"app","token":"t0k3n","expiry":"2030-01-02T03:04:05Z"}`), 0o600))
+	user, password, expiry, err = FileCredentials(path, time.Minute).Credentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "app", user)
+	assert.Equal(t, "t0k3n", password)
+	assert.True(t, at.Equal(expiry))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"password":"secret"`), 0o600))
+	_, _, _, err = FileCredentials(path, 0).Credentials(ctx)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+
+	_, _, _, err = FileCredentials(filepath.Join(dir, "missing"), 0).Credentials(ctx)
+	require.Error(t, err)
+}
+
+func TestEnvCredentials(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("HYPERSQL_TEST_USER", "app")
+	t.Setenv("HYPERSQL_TEST_PASSWORD", "secret")
+
+	user, password, _, err := EnvCredentials("HYPERSQL_TEST_USER", "HYPERSQL_TEST_PASSWORD").Credentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "app", user)
+	assert.Equal(t, "secret", password)
+
+	user, _, _, err = EnvCredentials("", "HYPERSQL_TEST_PASSWORD").Credentials(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, user)
+
+	_, _, _, err = EnvCredentials("", "HYPERSQL_TEST_MISSING").Credentials(ctx)
+	require.Error(t, err)
+}
+
+func TestExecCredentials(t *testing.T) {
+	ctx := context.Background()
+	user, password, expiry, err := ExecCredentials(time.Minute, "sh", "-c", `echo '{"username":"app","password":"secret"}'`).Credentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "app", user)
+	assert.Equal(t, "secret", password)
+	assert.False(t, expiry.IsZero())
+
+	_, _, _, err = ExecCredentials(0, "sh", "-c", "echo denied >&2; exit 1").Credentials(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "denied")
+}
+
+func TestCredentialProvider_Dialects(t *testing.T) {
+	cases := []struct {
+		dialect string
+		params  ConfigParams
+	}{
+		{dialect: DialectPostgres, params: ConfigParams{"sslmode": "disable"}},
+		{dialect: DialectMySQL},
+	}
+	for _, tc := range cases {
+		t.Run(tc.dialect, func(t *testing.T) {
+			ln := newTestListener(t)
+			var calls atomic.Int32
+			c := &Config{
+				Dialect:     tc.dialect,
+				Transport:   "tcp",
+				Name:        "test",
+				User:        "test",
+				DialTimeout: time.Second,
+				Hosts:       []HostPort{ln.hostPort()},
+				Params:      tc.params,
+				CredentialProvider: CredentialProviderFunc(func(ctx context.Context) (string, string, time.Time, error) {
+					calls.Add(1)
+					return "app", "token", time.Now().Add(time.Hour), nil
+				}),
+			}
+			connector, err := connectors[tc.dialect](context.Background(), c)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			// The listener is not a database, so the connection fails after the host is reached.
+			for range 2 {
+				_, err = connector.Connect(ctx)
+				require.Error(t, err)
+			}
+			assert.Positive(t, ln.accepted.Load())
+			// The credentials are cached until expiry.
+			assert.Equal(t, int32(1), calls.Load())
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		failure := errors.New("no credentials")
+		c := &Config{
+			Dialect:   DialectPostgres,
+			Transport: "tcp",
+			Name:      "test",
+			Hosts:     []HostPort{refusedHostPort(t)},
+			Params:    ConfigParams{"sslmode": "disable"},
+			CredentialProvider: CredentialProviderFunc(func(ctx context.Context) (string, string, time.Time, error) {
+				return "", "", time.Time{}, failure
+			}),
+		}
+		connector, err := connectors[DialectPostgres](context.Background(), c)
+		require.NoError(t, err)
+		_, err = connector.Connect(context.Background())
+		require.ErrorIs(t, err, failure)
+	})
+
+	t.Run("context", func(t *testing.T) {
+		for _, dialect := range []string{DialectPostgres, DialectMySQL} {
+			c := &Config{
+				Dialect:        dialect,
+				Transport:      "tcp",
+				Name:           "test",
+				Hosts:          []HostPort{refusedHostPort(t)},
+				DriverWrappers: DriverWrappers{FailoverDetector()},
+				CredentialProvider: CredentialProviderFunc(func(ctx context.Context) (string, string, time.Time, error) {
+					<-ctx.Done()
+					return "", "", time.Time{}, ctx.Err()
+				}),
+			}
+			connector, err := connectors[dialect](context.Background(), c)
+			require.NoError(t, err)
+			// The context of Connect reaches the provider through the driver wrappers.
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			_, err = connector.Connect(ctx)
+			cancel()
+			require.ErrorIs(t, err, context.DeadlineExceeded, dialect)
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package hypersql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/blink-io/hypersql/driver/wrapper"
+)
+
+// DefaultHealthCheckTimeout is used by HealthCheck when Config.HealthCheckTimeout is not set.
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+var (
+	_ IDB    = (*DB)(nil)
+	_ IDBExt = (*DB)(nil)
+)
+
+// DB wraps *sql.DB with the Config it is created from and the detected DBInfo.
+type DB struct {
+	*sql.DB
+
+	c *Config
+
+	info DBInfo
+
+	inflight *inflightTracker
+
+	idleKeeper *idleKeeper
+
+	mu            sync.Mutex
+	closed        bool
+	closeHandlers CloseHandlers
+	lastStats     sql.DBStats
+}
+
+// Open creates *DB from the given config.
+// The DBInfo of the returned *DB includes the server version if it can be detected.
+func Open(ctx context.Context, c *Config) (*DB, error) {
+	if c == nil {
+		return nil, ErrNilConfig
+	}
+
+	// The in-flight operations are tracked for Shutdown.
+	inflight := newInflightTracker()
+	oc := c.Clone()
+	oc.DriverWrappers = append(oc.DriverWrappers, inflight.wrap)
+
+	sqlDB, err := newSqlDB(ctx, oc)
+	if err != nil {
+		return nil, err
+	}
+
+	info := c.DBInfo()
+	info.Version = detectServerVersion(ctx, sqlDB, info.Dialect)
+
+	db := &DB{
+		DB:            sqlDB,
+		c:             c,
+		info:          info,
+		inflight:      inflight,
+		closeHandlers: slices.Clone(c.CloseHandlers),
+	}
+	if n := keptIdleConns(c); n > 0 {
+		db.idleKeeper = newIdleKeeper(sqlDB, n, DefaultMinIdleCheckInterval)
+	}
+	return db, nil
+}
+
+// SqlDB returns the underlying *sql.DB.
+func (d *DB) SqlDB() *sql.DB {
+	return d.DB
+}
+
+// DBInfo returns the info detected when the DB was opened.
+func (d *DB) DBInfo() DBInfo {
+	return d.info
+}
+
+// Config returns the config the DB is created from.
+func (d *DB) Config() *Config {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.c
+}
+
+// applyPool applies the pool settings of c in place, including MinIdleConns, and keeps c as the config of d.
+func (d *DB) applyPool(ctx context.Context, c *Config) {
+	configurePool(ctx, d.DB, c)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.c = c
+	if d.closed {
+		return
+	}
+	if d.idleKeeper != nil {
+		d.idleKeeper.Stop()
+		d.idleKeeper = nil
+	}
+	if n := keptIdleConns(c); n > 0 {
+		d.idleKeeper = newIdleKeeper(d.DB, n, DefaultMinIdleCheckInterval)
+	}
+}
+
+// OnClose adds a handler which will be executed when the DB is closed.
+func (d *DB) OnClose(h CloseHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closeHandlers = append(d.closeHandlers, h)
+}
+
+// Raw runs fn with the native driver connection of a connection from the pool, e.g. *stdlib.Conn of pgx for COPY.
+// The driver wrappers, including the one tracking the in-flight operations for Shutdown, are removed by UnwrapConn.
+func (d *DB) Raw(ctx context.Context, fn func(driverConn any) error) error {
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		return fn(UnwrapConn(driverConn))
+	})
+}
+
+// UnwrapConn returns the native driver connection under the one passed to sql.Conn.Raw,
+// by removing the wrappers of hypersql, sqlhooks and otelsql.
+func UnwrapConn(driverConn any) any {
+	return wrapper.UnwrapConn(driverConn)
+}
+
+// HealthCheck pings the database and executes the validation SQL if it is set.
+// The check is bounded by Config.HealthCheckTimeout.
+func (d *DB) HealthCheck(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, d.healthCheckTimeout())
+	defer cancel()
+
+	if err := DoPingContext(ctx, d.DB); err != nil {
+		return fmt.Errorf("health check ping failed: %w", err)
+	}
+	if q := d.Config().ValidationSQL; len(q) > 0 {
+		if _, err := d.DB.ExecContext(ctx, q); err != nil {
+			return fmt.Errorf("health check validation failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close executes the close handlers in reverse order and then closes the underlying *sql.DB.
+// It is safe to call Close more than once.
+func (d *DB) Close() error {
+	return d.close(context.Background())
+}
+
+// Shutdown stops new work, new calls get ErrShuttingDown while the statements of active transactions are still allowed.
+// It waits for the in-flight queries and transactions to finish until ctx is done,
+// then cancels the rest and closes the DB. The error of ctx is returned if the waiting is given up.
+func (d *DB) Shutdown(ctx context.Context) error {
+	err := d.inflight.shutdown(ctx)
+	return errors.Join(err, d.close(ctx))
+}
+
+func (d *DB) close(ctx context.Context) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	handlers := d.closeHandlers
+	keeper := d.idleKeeper
+	d.mu.Unlock()
+
+	if keeper != nil {
+		keeper.Stop()
+	}
+
+	var errs []error
+	for i := len(handlers) - 1; i >= 0; i-- {
+		if err := handlers[i](ctx, d.DB); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := d.DB.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (d *DB) healthCheckTimeout() time.Duration {
+	if t := d.Config().HealthCheckTimeout; t > 0 {
+		return t
+	}
+	return DefaultHealthCheckTimeout
+}
+
+// detectServerVersion returns the server version, or empty if it cannot be detected.
+func detectServerVersion(ctx context.Context, db *sql.DB, dialect string) string {
+	q, ok := versionQueries[dialect]
+	if !ok {
+		return ""
+	}
+	var ver string
+	if err := db.QueryRowContext(ctx, q).Scan(&ver); err != nil {
+		return ""
+	}
+	return ver
+}
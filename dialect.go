@@ -6,6 +6,7 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/blink-io/hypersql/driver/wrapper"
 	"github.com/qustavo/sqlhooks/v2"
 )
 
@@ -29,6 +30,13 @@ var (
 	dialecters = make(map[string]func(string) bool)
 
 	dsners = make(map[string]Dsner)
+
+	// versionQueries holds the SQL to query server version for each dialect.
+	versionQueries = make(map[string]string)
+
+	// readOnlyQueries holds the SQL to query whether the server is read-only for each dialect,
+	// the next one is tried when a query fails, e.g. for the variables unknown to older servers.
+	readOnlyQueries = make(map[string][]string)
 )
 
 func RegisterConnector(dialect string, connector ConnectorFunc) {
@@ -58,6 +66,10 @@ func GetFormalDialect(dialect string) string {
 
 // IsCompatibleDialect checks
 func IsCompatibleDialect(dialect string) (string, bool) {
+	// A registered dialect is formal itself, e.g. sqlite3 is not reported as sqlite.
+	if _, ok := dialecters[dialect]; ok {
+		return dialect, true
+	}
 	for k, v := range dialecters {
 		if v(dialect) {
 			return k, true
@@ -79,7 +91,7 @@ func WrapDriver(drv driver.Driver, wrappers DriverWrappers, hooks DriverHooks) d
 
 func wrapDriverHooks(drv driver.Driver, drvHooks ...sqlhooks.Hooks) driver.Driver {
 	if len(drvHooks) > 0 {
-		drv = sqlhooks.Wrap(drv, sqlhooks.Compose(drvHooks...))
+		drv = wrapper.WrapHooks(drv, sqlhooks.Compose(drvHooks...))
 	}
 	return drv
 }
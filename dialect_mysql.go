@@ -1,20 +1,32 @@
 package hypersql
 
 import (
+	"cmp"
 	"context"
+	"crypto/tls"
+	"database/sql"
 	"database/sql/driver"
 	"errors"
 	"fmt"
-	"net"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/blink-io/hypersql/mysql/logger"
 	mysqlparams "github.com/blink-io/hypersql/mysql/params"
 	"github.com/go-sql-driver/mysql"
-	"github.com/spf13/cast"
 	"github.com/xo/dburl"
 )
 
+// ErrMySQLTLSDSN is returned by ToMySQLDSN when the config has TLS.
+var ErrMySQLTLSDSN = errors.New("MySQL TLS config can not be formatted into DSN, use ToMySQLTLSDSN")
+
+var mysqlTLSKeySeq atomic.Uint64
+
 var compatibleMySQLDialects = []string{
 	DialectMySQL,
 	"mysql5",
@@ -26,6 +38,24 @@ func init() {
 	connectors[dialect] = GetMySQLConnector
 	dialecters[dialect] = IsCompatibleMySQLDialect
 	dsners[dialect] = ToMySQLDSN
+	versionQueries[dialect] = "SELECT VERSION()"
+	readOnlyQueries[dialect] = []string{
+		"SELECT @@global.read_only OR @@global.super_read_only",
+		// MariaDB and MySQL before 5.7 have no super_read_only.
+		"SELECT @@global.read_only",
+	}
+	lagProbes[dialect] = LagProbeFunc(mysqlLag)
+	poolSizers[dialect] = serverMaxConnsPoolSizer("SELECT @@max_connections")
+	extraTypes[dialect] = reflect.TypeFor[MySQLExtra]()
+	knownParams[dialect] = mysqlparams.ConnParams.Exists
+	tlsParams[dialect] = []string{
+		mysqlparams.ConnParams.SSLCA,
+		mysqlparams.ConnParams.SSLCAPath,
+		mysqlparams.ConnParams.SSLCert,
+		mysqlparams.ConnParams.SSLKey,
+		mysqlparams.ConnParams.SSLCRL,
+		mysqlparams.ConnParams.TLSVersion,
+	}
 }
 
 type MySQLExtra struct {
@@ -43,8 +73,14 @@ type MySQLExtra struct {
 var _ Validator = (*MySQLExtra)(nil)
 
 func (c *MySQLExtra) Validate(ctx context.Context) error {
-	if c == nil {
-
+	if c == nil || len(c.ConnectionAttributes) == 0 {
+		return nil
+	}
+	// The attributes are like "key1:value1,key2:value2"
+	for _, attr := range strings.Split(c.ConnectionAttributes, ",") {
+		if k, _, ok := strings.Cut(attr, ":"); !ok || len(strings.TrimSpace(k)) == 0 {
+			return fmt.Errorf("invalid ConnectionAttributes %q", attr)
+		}
 	}
 	return nil
 }
@@ -64,15 +100,96 @@ func GetMySQLDriver(dialect string) (driver.Driver, error) {
 }
 
 func GetMySQLConnector(ctx context.Context, c *Config) (driver.Connector, error) {
-	cc, err := ToMySQLConfig(c)
+	cc, tlsConfig, err := toMySQLConfig(c)
 	if err != nil {
 		return nil, err
 	}
-	dsn := cc.FormatDSN()
-	drv := WrapDriver(RawMySQLDriver(), c.DriverWrappers, c.DriverHooks)
-	return &dsnConnector{dsn: dsn, drv: drv}, nil
+	// The TLS configs are registered for each host, and deregistered when the connector is closed.
+	tc := &mysqlTLSConnector{}
+	register := func(hc *mysql.Config, host string) error {
+		if tlsConfig == nil {
+			return nil
+		}
+		key, err := registerMySQLTLSConfig(c.Name, tlsConfigForHost(tlsConfig, host))
+		if err != nil {
+			return err
+		}
+		hc.TLSConfig = key
+		tc.keys = append(tc.keys, key)
+		return nil
+	}
+	creds := credentialsFor(c)
+	connector := func(hc *mysql.Config) (driver.Connector, error) {
+		if creds == nil {
+			drv := WrapDriver(RawMySQLDriver(), c.DriverWrappers, c.DriverHooks)
+			return &dsnConnector{dsn: hc.FormatDSN(), drv: drv}, nil
+		}
+		err := hc.Apply(mysql.BeforeConnect(func(ctx context.Context, hc *mysql.Config) error {
+			user, password, _, err := creds.Credentials(ctx)
+			if err != nil {
+				return err
+			}
+			if len(user) > 0 {
+				hc.User = user
+			}
+			hc.Passwd = password
+			return nil
+		}))
+		if err != nil {
+			return nil, err
+		}
+		mc, err := mysql.NewConnector(hc)
+		if err != nil {
+			return nil, err
+		}
+		return newContextConnector(mc, c.DriverWrappers, c.DriverHooks), nil
+	}
+	if cc.Net != "tcp" {
+		if err := register(cc, ""); err != nil {
+			return nil, err
+		}
+		if tc.Connector, err = connector(cc); err != nil {
+			_ = tc.Close()
+			return nil, err
+		}
+		return tc, nil
+	}
+
+	var connectors []driver.Connector
+	for _, h := range c.HostPorts() {
+		hc := cc.Clone()
+		hc.Addr = h.String()
+		if err := register(hc, h.Host); err != nil {
+			_ = tc.Close()
+			return nil, err
+		}
+		hconn, err := connector(hc)
+		if err != nil {
+			_ = tc.Close()
+			return nil, err
+		}
+		connectors = append(connectors, hconn)
+	}
+	tc.Connector = newFailoverConnector(connectors, c.ShuffleHosts)
+	return tc, nil
+}
+
+// mysqlTLSConnector deregisters the TLS configs of keys when it is closed, sql.DB closes it.
+type mysqlTLSConnector struct {
+	driver.Connector
+	keys []string
 }
 
+func (c *mysqlTLSConnector) Close() error {
+	for _, key := range c.keys {
+		mysql.DeregisterTLSConfig(key)
+	}
+	c.keys = nil
+	return nil
+}
+
+var _ io.Closer = (*mysqlTLSConnector)(nil)
+
 func (c *Config) ToMySQL() {
 	c.Dialect = DialectMySQL
 	c.Port = 3306
@@ -95,23 +212,60 @@ func ToMySQLConfigFromURL(url string) (*mysql.Config, error) {
 	return ToMySQLConfigFromDSN(dsn)
 }
 
+// ToMySQLDSN converts the config to MySQL DSN. The TLS config can not be referred by a DSN
+// without being registered, so ErrMySQLTLSDSN is returned when it is set, use ToMySQLTLSDSN instead.
 func ToMySQLDSN(ctx context.Context, c *Config) (string, error) {
 	cc, err := ToMySQLConfig(c)
 	if err != nil {
 		return "", err
 	}
+	if cc.TLS != nil {
+		return "", ErrMySQLTLSDSN
+	}
 	return cc.FormatDSN(), nil
 }
 
+// ToMySQLTLSDSN converts the config to MySQL DSN like ToMySQLDSN, and registers the TLS config
+// under a unique key which the DSN refers to. Call deregister when the DSN is not used any more.
+func ToMySQLTLSDSN(ctx context.Context, c *Config) (dsn string, deregister func(), err error) {
+	cc, err := ToMySQLConfig(c)
+	if err != nil {
+		return "", nil, err
+	}
+	deregister = func() {}
+	if cc.TLS != nil {
+		key, err := registerMySQLTLSConfig(c.Name, cc.TLS)
+		if err != nil {
+			return "", nil, err
+		}
+		cc.TLS, cc.TLSConfig = nil, key
+		deregister = func() { mysql.DeregisterTLSConfig(key) }
+	}
+	return cc.FormatDSN(), deregister, nil
+}
+
+// ToMySQLConfig converts the config to MySQL config. The TLS config is set to TLS without being registered,
+// so it is used by mysql.NewConnector but not kept by FormatDSN.
 func ToMySQLConfig(c *Config) (*mysql.Config, error) {
+	cc, tlsConfig, err := toMySQLConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil && cc.Net == "tcp" {
+		tlsConfig = tlsConfigForHost(tlsConfig, c.HostPorts()[0].Host)
+	}
+	cc.TLS = tlsConfig
+	return cc, nil
+}
+
+// toMySQLConfig converts the config to MySQL config, and returns the TLS config which is not registered yet.
+func toMySQLConfig(c *Config) (*mysql.Config, *tls.Config, error) {
 	network := c.Transport
 	name := c.Name
-	host := c.Host
-	port := c.Port
+	host := c.HostPorts()[0]
 	user := c.User
 	password := c.Password
 	dialTimeout := c.DialTimeout
-	tlsConfig := c.TLSConfig
 	loc := c.Loc
 	params := c.Params
 	if loc == nil {
@@ -136,30 +290,17 @@ func ToMySQLConfig(c *Config) (*mysql.Config, error) {
 	}
 
 	if network == "tcp" {
-		cc.Addr = net.JoinHostPort(host, cast.ToString(port))
+		cc.Addr = host.String()
 	} else {
 		// Otherwise, addr is Unix domain sockets
-		cc.Addr = host
-	}
-	if tlsConfig != nil {
-		keyName := mysqlTLSKeyName(name)
-		_ = mysql.RegisterTLSConfig(keyName, tlsConfig)
-		cc.TLSConfig = keyName
-	} else if c.TLSCert != nil {
-		tlscnf, err := CreateClientTLSConfig(
-			c.TLSCert.CAFile,
-			c.TLSCert.CAOptional,
-			c.TLSCert.CertFile,
-			c.TLSCert.KeyFile,
-			c.TLSCert.InsecureSkipVerify,
-		)
-		if err != nil {
-			return nil, errors.New("invalid ca file or key file")
-		}
-		keyName := mysqlTLSKeyName(name)
-		_ = mysql.RegisterTLSConfig(keyName, tlscnf)
-		cc.TLSConfig = keyName
+		cc.Addr = host.Host
 	}
+	tlsConfig, tlsMode, err := clientTLS(c, mysqlTLSCertFromParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The server without TLS is allowed in prefer mode.
+	cc.AllowFallbackToPlaintext = tlsConfig != nil && tlsMode == TLSModePrefer
 	if l := c.Logger; l != nil {
 		cc.Logger = logger.Logf(func(v ...any) {
 			l(fmt.Sprint(v...))
@@ -175,10 +316,10 @@ func ToMySQLConfig(c *Config) (*mysql.Config, error) {
 	}
 
 	if err := handleMySQLParams(params, cc); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return cc, nil
+	return cc, tlsConfig, nil
 }
 
 func IsCompatibleMySQLDialect(dialect string) bool {
@@ -189,8 +330,108 @@ func RawMySQLDriver() driver.Driver {
 	return &mysql.MySQLDriver{}
 }
 
+// mysqlSSLModes maps the ssl-mode of MySQL to the TLS modes.
+var mysqlSSLModes = map[string]string{
+	"DISABLED":        TLSModeDisable,
+	"PREFERRED":       TLSModePrefer,
+	"REQUIRED":        TLSModeRequire,
+	"VERIFY_CA":       TLSModeVerifyCA,
+	"VERIFY_IDENTITY": TLSModeVerifyFull,
+}
+
+// mysqlTLSCertFromParams converts ssl-mode, ssl-ca, ssl-cert, ssl-key, ssl-crl and tls-version to TLSCert,
+// nil is returned if none of them is set. The mode is PREFERRED by default as the mysql client,
+// and the lowest one of tls-version is the minimum version.
+func mysqlTLSCertFromParams(params ConfigParams) *TLSCert {
+	p := mysqlparams.ConnParams
+	if !slices.ContainsFunc([]string{p.SSLMode, p.SSLCA, p.SSLCert, p.SSLKey, p.SSLCRL, p.TLSVersion}, params.Exists) {
+		return nil
+	}
+	t := &TLSCert{
+		Mode:     TLSModePrefer,
+		CAFile:   params.Get(p.SSLCA),
+		CertFile: params.Get(p.SSLCert),
+		KeyFile:  params.Get(p.SSLKey),
+		CRLFile:  params.Get(p.SSLCRL),
+	}
+	params.IfNotEmpty(p.SSLMode, func(v string) {
+		if mode, ok := mysqlSSLModes[strings.ToUpper(v)]; ok {
+			t.Mode = mode
+		} else {
+			// It is reported by TLSCert.TLSMode.
+			t.Mode = v
+		}
+	})
+	params.IfNotEmpty(p.TLSVersion, func(v string) {
+		versions := strings.Split(v, ",")
+		for i := range versions {
+			versions[i] = strings.TrimSpace(versions[i])
+		}
+		// The invalid version is the lowest one, so it is reported too.
+		t.MinVersion = slices.MinFunc(versions, func(a, b string) int {
+			va, _ := parseTLSVersion(a)
+			vb, _ := parseTLSVersion(b)
+			return cmp.Compare(va, vb)
+		})
+	})
+	return t
+}
+
+// mysqlTLSKeyName returns a unique key of the database name, so the configs of the same database
+// on different servers do not overwrite each other.
 func mysqlTLSKeyName(name string) string {
-	return DialectMySQL + "_" + name
+	return DialectMySQL + "_" + name + "_" + strconv.FormatUint(mysqlTLSKeySeq.Add(1), 10)
+}
+
+func registerMySQLTLSConfig(name string, cfg *tls.Config) (string, error) {
+	key := mysqlTLSKeyName(name)
+	if err := mysql.RegisterTLSConfig(key, cfg); err != nil {
+		return "", fmt.Errorf("unable to register MySQL TLS config: %w", err)
+	}
+	return key, nil
+}
+
+// mysqlLag reads Seconds_Behind_Source of SHOW REPLICA STATUS,
+// or Seconds_Behind_Master of SHOW SLAVE STATUS before MySQL 8.0.22.
+func mysqlLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		if rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS"); err != nil {
+			return 0, err
+		}
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		// Not a replica
+		return 0, rows.Err()
+	}
+	values := make([]sql.NullString, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+	for i, col := range cols {
+		if col != "Seconds_Behind_Source" && col != "Seconds_Behind_Master" {
+			continue
+		}
+		if !values[i].Valid {
+			return 0, ErrReplicationNotRunning
+		}
+		secs, err := strconv.ParseInt(values[i].String, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(secs) * time.Second, nil
+	}
+	return 0, errors.New("no seconds behind source in replica status")
 }
 
 func handleMySQLParams(params ConfigParams, cc *mysql.Config) error {
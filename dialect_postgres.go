@@ -2,12 +2,15 @@ package hypersql
 
 import (
 	"context"
-	"crypto/tls"
 	"database/sql/driver"
-	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"slices"
 	"sort"
 	"strings"
 
+	pgparams "github.com/blink-io/hypersql/postgres/params"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -20,6 +23,20 @@ func init() {
 	connectors[dialect] = GetPostgresConnector
 	dialecters[dialect] = IsCompatiblePostgresDialect
 	dsners[dialect] = ToPostgresDSN
+	versionQueries[dialect] = "SHOW server_version"
+	readOnlyQueries[dialect] = []string{"SELECT pg_is_in_recovery() OR current_setting('transaction_read_only')::bool"}
+	lagProbes[dialect] = secondsLagProbe(postgresLagQuery)
+	poolSizers[dialect] = serverMaxConnsPoolSizer("SELECT current_setting('max_connections')::int - current_setting('superuser_reserved_connections')::int")
+	extraTypes[dialect] = reflect.TypeFor[PostgresExtra]()
+	knownParams[dialect] = func(key string) bool {
+		return pgparams.ConnParams.Exists(key) || pgparams.RuntimeParams.Exists(key)
+	}
+	tlsParams[dialect] = []string{
+		pgparams.ConnParams.SSLRootCert,
+		pgparams.ConnParams.SSLCert,
+		pgparams.ConnParams.SSLKey,
+		pgparams.ConnParams.SSLCRL,
+	}
 }
 
 var compatiblePostgresDialects = []string{
@@ -29,6 +46,14 @@ var compatiblePostgresDialects = []string{
 	"pgx",
 }
 
+// postgresLagQuery treats a replica which has replayed all received WAL as no lag,
+// otherwise the lag grows with the idle time of the primary.
+const postgresLagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN 0
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())::float8
+END`
+
 type PostgresExtra struct {
 	DialFunc pgconn.DialFunc
 
@@ -51,7 +76,13 @@ var _ Validator = (*PostgresExtra)(nil)
 
 func (c *PostgresExtra) Validate(ctx context.Context) error {
 	if c == nil {
-
+		return nil
+	}
+	if c.StatementCacheCapacity < 0 {
+		return fmt.Errorf("StatementCacheCapacity %d is negative", c.StatementCacheCapacity)
+	}
+	if c.DescriptionCacheCapacity < 0 {
+		return fmt.Errorf("DescriptionCacheCapacity %d is negative", c.DescriptionCacheCapacity)
 	}
 	return nil
 }
@@ -75,11 +106,60 @@ func GetPostgresConnector(ctx context.Context, c *Config) (driver.Connector, err
 	if err != nil {
 		return nil, err
 	}
+	creds := credentialsFor(c)
+	if creds != nil || c.ShuffleHosts {
+		pgc := stdlib.GetConnector(*cc, stdlib.OptionBeforeConnect(func(ctx context.Context, cc *pgx.ConnConfig) error {
+			if c.ShuffleHosts {
+				shufflePostgresHosts(cc)
+			}
+			if creds == nil {
+				return nil
+			}
+			user, password, _, err := creds.Credentials(ctx)
+			if err != nil {
+				return err
+			}
+			if len(user) > 0 {
+				cc.User = user
+			}
+			cc.Password = password
+			return nil
+		}))
+		return newContextConnector(pgc, c.DriverWrappers, c.DriverHooks), nil
+	}
+
 	dsn := stdlib.RegisterConnConfig(cc)
 	drv := WrapDriver(RawPostgresDriver(), c.DriverWrappers, c.DriverHooks)
 	return &dsnConnector{dsn: dsn, drv: drv}, nil
 }
 
+// shufflePostgresHosts shuffles the host order of cc for a connection, as the other dialects do.
+// The fallbacks of the same host, i.e. TLS and plaintext in prefer mode, are kept together and in order.
+// cc is a shallow copy per connection, so its fallbacks are replaced rather than shuffled in place.
+func shufflePostgresHosts(cc *pgx.ConnConfig) {
+	all := append([]*pgconn.FallbackConfig{{
+		Host:      cc.Host,
+		Port:      cc.Port,
+		TLSConfig: cc.TLSConfig,
+	}}, cc.Fallbacks...)
+	var groups [][]*pgconn.FallbackConfig
+	for i, fc := range all {
+		if i > 0 && fc.Host == all[i-1].Host && fc.Port == all[i-1].Port {
+			groups[len(groups)-1] = append(groups[len(groups)-1], fc)
+		} else {
+			groups = append(groups, []*pgconn.FallbackConfig{fc})
+		}
+	}
+	rand.Shuffle(len(groups), func(i, j int) {
+		groups[i], groups[j] = groups[j], groups[i]
+	})
+	all = slices.Concat(groups...)
+	cc.Host = all[0].Host
+	cc.Port = all[0].Port
+	cc.TLSConfig = all[0].TLSConfig
+	cc.Fallbacks = all[1:]
+}
+
 func (c *Config) ToPostgres() {
 	c.Dialect = DialectPostgres
 	c.Port = 5432
@@ -115,10 +195,16 @@ func ToPostgresDSN(ctx context.Context, c *Config) (string, error) {
 		}
 	}
 
+	var hosts, ports []string
+	for _, h := range c.HostPorts() {
+		hosts = append(hosts, h.Host)
+		ports = append(ports, cast.ToString(h.Port))
+	}
+
 	accrue("user", c.User)
 	accrue("password", c.Password)
-	accrue("host", c.Host)
-	accrue("port", cast.ToString(c.Port))
+	accrue("host", strings.Join(hosts, ","))
+	accrue("port", strings.Join(ports, ","))
 	accrue("dbname", c.Name)
 
 	q := c.Params
@@ -131,10 +217,8 @@ func ToPostgresDSN(ctx context.Context, c *Config) (string, error) {
 }
 
 func ToPostgresConfig(c *Config) (*pgx.ConnConfig, error) {
-	var tlsConfig *tls.Config
 	name := c.Name
-	host := c.Host
-	port := c.Port
+	hosts := c.HostPorts()
 	user := c.User
 	password := c.Password
 	dialTimeout := c.DialTimeout
@@ -148,28 +232,32 @@ func ToPostgresConfig(c *Config) (*pgx.ConnConfig, error) {
 		return nil, err
 	}
 
-	if c.TLSConfig != nil {
-		tlsConfig = c.TLSConfig
-	} else if c.TLSCert != nil {
-		tlscnf, err := CreateClientTLSConfig(
-			c.TLSCert.CAFile,
-			c.TLSCert.CAOptional,
-			c.TLSCert.CertFile,
-			c.TLSCert.KeyFile,
-			c.TLSCert.InsecureSkipVerify,
-		)
-		if err != nil {
-			return nil, errors.New("invalid ca file or key file")
-		}
-		tlsConfig = tlscnf
+	tlsConfig, tlsMode, err := clientTLS(c, postgresTLSCertFromParams)
+	if err != nil {
+		return nil, err
 	}
 
 	pgcc.Database = name
-	pgcc.Host = host
-	pgcc.Port = uint16(port)
+	// The other hosts are tried in order if the first one fails,
+	// and each host is tried without TLS after TLS in prefer mode, as libpq does.
+	// ShuffleHosts shuffles them per connection in GetPostgresConnector.
+	var fallbacks []*pgconn.FallbackConfig
+	for _, h := range hosts {
+		fallbacks = append(fallbacks, &pgconn.FallbackConfig{
+			Host:      h.Host,
+			Port:      uint16(h.Port),
+			TLSConfig: tlsConfigForHost(tlsConfig, h.Host),
+		})
+		if tlsConfig != nil && tlsMode == TLSModePrefer {
+			fallbacks = append(fallbacks, &pgconn.FallbackConfig{Host: h.Host, Port: uint16(h.Port)})
+		}
+	}
+	pgcc.Host = fallbacks[0].Host
+	pgcc.Port = fallbacks[0].Port
+	pgcc.TLSConfig = fallbacks[0].TLSConfig
+	pgcc.Fallbacks = fallbacks[1:]
 	pgcc.User = user
 	pgcc.Password = password
-	pgcc.TLSConfig = tlsConfig
 	if dialTimeout > 0 {
 		pgcc.ConnectTimeout = dialTimeout
 	}
@@ -196,9 +284,34 @@ func ToPostgresConfig(c *Config) (*pgx.ConnConfig, error) {
 		return nil, err
 	}
 
+	// ValidateConnect of extra takes precedence over target_session_attrs
+	if ext, ok := c.Extra.(*PostgresExtra); ok && ext != nil && ext.ValidateConnect != nil {
+		cc.ValidateConnect = ext.ValidateConnect
+	}
+
 	return cc, nil
 }
 
+// postgresTLSCertFromParams converts sslmode, sslrootcert, sslcert, sslkey and sslcrl to TLSCert,
+// nil is returned if none of them is set. The mode is prefer by default, and allow is the same as prefer.
+func postgresTLSCertFromParams(params ConfigParams) *TLSCert {
+	p := pgparams.ConnParams
+	if !slices.ContainsFunc([]string{p.SSLMode, p.SSLRootCert, p.SSLCert, p.SSLKey, p.SSLCRL}, params.Exists) {
+		return nil
+	}
+	t := &TLSCert{
+		Mode:     params.Get(p.SSLMode),
+		CAFile:   params.Get(p.SSLRootCert),
+		CertFile: params.Get(p.SSLCert),
+		KeyFile:  params.Get(p.SSLKey),
+		CRLFile:  params.Get(p.SSLCRL),
+	}
+	if len(t.Mode) == 0 || t.Mode == "allow" {
+		t.Mode = TLSModePrefer
+	}
+	return t
+}
+
 func RawPostgresDriver() driver.Driver {
 	// Notes: Unable to invoke &stdlib.Driver{} directly.
 	// Because the "configs" field inside the drv is not initialized.
@@ -210,6 +323,27 @@ func handlePostgresParams(params ConfigParams, cc *pgx.ConnConfig) error {
 	for k, v := range params {
 		newParams[k] = v
 	}
+
+	if err := params.IfNotEmptyWithErr(pgparams.ConnParams.TargetSessionAttrs, func(v string) error {
+		switch v {
+		case "read-write":
+			cc.ValidateConnect = pgconn.ValidateConnectTargetSessionAttrsReadWrite
+		case "read-only":
+			cc.ValidateConnect = pgconn.ValidateConnectTargetSessionAttrsReadOnly
+		case "primary":
+			cc.ValidateConnect = pgconn.ValidateConnectTargetSessionAttrsPrimary
+		case "standby":
+			cc.ValidateConnect = pgconn.ValidateConnectTargetSessionAttrsStandby
+		case "prefer-standby":
+			cc.ValidateConnect = pgconn.ValidateConnectTargetSessionAttrsPreferStandby
+		case "any":
+		default:
+			return fmt.Errorf("invalid target_session_attrs '%s'", v)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -5,8 +5,10 @@ package hypersql
 import (
 	"context"
 	"database/sql/driver"
+	"reflect"
 
 	"github.com/blink-io/hypersql/sqlite"
+	sqliteparams "github.com/blink-io/hypersql/sqlite/params"
 	"github.com/xo/dburl"
 )
 
@@ -21,10 +23,21 @@ func init() {
 	connectors[DialectSQLite] = GetSQLiteConnector
 	dialecters[DialectSQLite] = IsCompatibleSQLiteDialect
 	dsners[DialectSQLite] = ToSQLiteDSN
+	versionQueries[DialectSQLite] = "SELECT sqlite_version()"
+	readOnlyQueries[DialectSQLite] = []string{"PRAGMA query_only"}
+	// SQLite allows only one writer at a time
+	poolSizers[DialectSQLite] = fixedPoolSizer(1)
+	extraTypes[DialectSQLite] = reflect.TypeFor[SQLiteExtra]()
+	knownParams[DialectSQLite] = sqliteparams.ConnParams.Exists
 
 	connectors[DialectSQLite3] = GetSQLiteConnector
 	dialecters[DialectSQLite3] = IsCompatibleSQLiteDialect
 	dsners[DialectSQLite3] = ToSQLiteDSN
+	versionQueries[DialectSQLite3] = "SELECT sqlite_version()"
+	readOnlyQueries[DialectSQLite3] = []string{"PRAGMA query_only"}
+	poolSizers[DialectSQLite3] = fixedPoolSizer(1)
+	extraTypes[DialectSQLite3] = reflect.TypeFor[SQLiteExtra]()
+	knownParams[DialectSQLite3] = sqliteparams.ConnParams.Exists
 }
 
 func GetSQLiteDSN(dialect string) (Dsner, error) {
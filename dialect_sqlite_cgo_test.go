@@ -11,7 +11,7 @@ import (
 )
 
 func Test_SQLite3_CGO_1(t *testing.T) {
-	drv := getRawSQLiteDriver()
+	drv := RawSQLiteDriver()
 	sql.Register("sqlite_cgo_free", drv)
 	db, err := sql.Open("sqlite_cgo_free", ":memory:")
 	require.NoError(t, err)
@@ -3,15 +3,18 @@
 package hypersql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"testing"
 
+	"github.com/qustavo/sqlhooks/v2/hooks/loghooks"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func Test_SQLite3_NOCGO_1(t *testing.T) {
-	drv := getRawSQLiteDriver()
+	drv := RawSQLiteDriver()
 	sql.Register("sqlite_cgo_free", drv)
 	db, err := sql.Open("sqlite_cgo_free", ":memory:")
 	require.NoError(t, err)
@@ -22,3 +25,20 @@ func Test_SQLite3_NOCGO_1(t *testing.T) {
 
 	fmt.Println("SQLite Version:", str)
 }
+
+func TestDB_Raw(t *testing.T) {
+	ctx := context.Background()
+	c := newSQLiteTestConfig("raw.db")
+	c.DriverWrappers = DriverWrappers{FailoverDetector()}
+	c.DriverHooks = DriverHooks{loghooks.New()}
+	db, err := Open(ctx, c)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Raw(ctx, func(driverConn any) error {
+		_, ok := driverConn.(interface{ Serialize() ([]byte, error) })
+		assert.True(t, ok, "%T is not the modernc.org/sqlite conn", driverConn)
+		return nil
+	})
+	require.NoError(t, err)
+}
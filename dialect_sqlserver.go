@@ -2,10 +2,10 @@ package hypersql
 
 import (
 	"context"
-	"crypto/tls"
 	"database/sql/driver"
-	"errors"
 	"fmt"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -20,6 +20,16 @@ func init() {
 	connectors[dialect] = GetSQLServerConnector
 	dialecters[dialect] = IsCompatibleSQLServerDialect
 	dsners[dialect] = ToSQLServerDSN
+	versionQueries[dialect] = "SELECT CAST(SERVERPROPERTY('ProductVersion') AS NVARCHAR(128))"
+	readOnlyQueries[dialect] = []string{"SELECT CASE WHEN DATABASEPROPERTYEX(DB_NAME(), 'Updateability') = 'READ_ONLY' THEN 1 ELSE 0 END"}
+	lagProbes[dialect] = secondsLagProbe(sqlServerLagQuery)
+	extraTypes[dialect] = reflect.TypeFor[SQLServerExtra]()
+	knownParams[dialect] = mssqlparams.ConnParams.Exists
+	tlsParams[dialect] = []string{
+		mssqlparams.ConnParams.Certificate,
+		mssqlparams.ConnParams.TrustServerCertificate,
+		mssqlparams.ConnParams.TLSMin,
+	}
 }
 
 var compatibleSQLServerDialects = []string{
@@ -28,15 +38,24 @@ var compatibleSQLServerDialects = []string{
 	"mssqldb",
 }
 
+// sqlServerLagQuery measures the lag of the local secondary replica in an availability group.
+// A replica which has received and redone all log is treated as no lag, otherwise the lag grows
+// with the idle time of the primary, and a suspended one is not replicating.
+// See https://learn.microsoft.com/en-us/sql/relational-databases/system-dynamic-management-views/sys-dm-hadr-database-replica-states-transact-sql
+const sqlServerLagQuery = `SELECT CASE
+	WHEN is_suspended = 1 THEN NULL
+	WHEN log_send_queue_size = 0 AND redo_queue_size = 0 THEN 0
+	ELSE CAST(DATEDIFF(MILLISECOND, last_commit_time, SYSDATETIME()) AS FLOAT) / 1000
+END
+FROM sys.dm_hadr_database_replica_states
+WHERE is_local = 1 AND is_primary_replica = 0 AND database_id = DB_ID()`
+
 type SQLServerExtra struct {
 }
 
 var _ Validator = (*SQLServerExtra)(nil)
 
 func (c *SQLServerExtra) Validate(ctx context.Context) error {
-	if c == nil {
-
-	}
 	return nil
 }
 
@@ -59,9 +78,45 @@ func GetSQLServerConnector(ctx context.Context, c *Config) (driver.Connector, er
 	if err != nil {
 		return nil, err
 	}
-	dsn := cc.URL().String()
 	drv := WrapDriver(RawSQLServerDriver(), c.DriverWrappers, c.DriverHooks)
-	return &dsnConnector{dsn: dsn, drv: drv}, nil
+	creds := credentialsFor(c)
+
+	hosts := c.HostPorts()
+	var connectors []driver.Connector
+	for _, h := range hosts {
+		hc := *cc
+		hc.Host, hc.Port = h.Host, uint64(h.Port)
+		// The failover connector tries the hosts, so a host is not the failover partner of another.
+		if len(hosts) > 1 {
+			hc.FailOverPartner, hc.FailOverPort = "", 0
+		}
+		if creds == nil && hc.TLSConfig == nil {
+			connectors = append(connectors, &dsnConnector{dsn: sqlServerURL(&hc), drv: drv})
+			continue
+		}
+		// The URL does not keep the TLS config, so the connector is created by the parsed config.
+		pc, err := msdsn.Parse(sqlServerURL(&hc))
+		if err != nil {
+			return nil, err
+		}
+		if hc.TLSConfig != nil {
+			pc.TLSConfig = tlsConfigForHost(hc.TLSConfig, h.Host)
+			pc.HostInCertificateProvided = hc.HostInCertificateProvided || len(hc.TLSConfig.ServerName) > 0
+		}
+		var mc driver.Connector = mssql.NewConnectorConfig(pc)
+		if creds != nil {
+			// The password of provider is sent as the access token, e.g. of Microsoft Entra ID.
+			mc, err = mssql.NewSecurityTokenConnector(pc, func(ctx context.Context) (string, error) {
+				_, token, _, err := creds.Credentials(ctx)
+				return token, err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		connectors = append(connectors, newContextConnector(mc, c.DriverWrappers, c.DriverHooks))
+	}
+	return newFailoverConnector(connectors, c.ShuffleHosts), nil
 }
 
 func (c *Config) ToSQLServer() {
@@ -93,14 +148,26 @@ func ToSQLServerDSN(ctx context.Context, c *Config) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return cc.URL().String(), nil
+	return sqlServerURL(cc), nil
+}
+
+// sqlServerURL formats the config to URL, msdsn.Config.URL does not keep the failover partner.
+func sqlServerURL(cc *msdsn.Config) string {
+	u := cc.URL()
+	if len(cc.FailOverPartner) > 0 {
+		q := u.Query()
+		q.Set(mssqlparams.ConnParams.FailoverPartner, cc.FailOverPartner)
+		if cc.FailOverPort > 0 {
+			q.Set(mssqlparams.ConnParams.FailOverPort, strconv.FormatUint(cc.FailOverPort, 10))
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
 }
 
 func ToSQLServerConfig(c *Config) (*msdsn.Config, error) {
-	var tlsConfig *tls.Config
 	name := c.Name
-	host := c.Host
-	port := c.Port
+	hosts := c.HostPorts()
 	user := c.User
 	password := c.Password
 	dialTimeout := c.DialTimeout
@@ -111,29 +178,31 @@ func ToSQLServerConfig(c *Config) (*msdsn.Config, error) {
 
 	cc := &msdsn.Config{
 		Database: name,
-		Host:     host,
-		Port:     uint64(port),
+		Host:     hosts[0].Host,
+		Port:     uint64(hosts[0].Port),
 		User:     user,
 		Password: password,
 	}
-
-	if c.TLSConfig != nil {
-		tlsConfig = c.TLSConfig
-	} else if c.TLSCert != nil {
-		tlscnf, err := CreateClientTLSConfig(
-			c.TLSCert.CAFile,
-			c.TLSCert.CAOptional,
-			c.TLSCert.CertFile,
-			c.TLSCert.KeyFile,
-			c.TLSCert.InsecureSkipVerify,
-		)
-		if err != nil {
-			return nil, errors.New("invalid ca file or key file")
-		}
-		tlsConfig = tlscnf
+	// The second host is the failover partner unless it is set by params.
+	if len(hosts) > 1 {
+		cc.FailOverPartner = hosts[1].Host
+		cc.FailOverPort = uint64(hosts[1].Port)
 	}
 
+	tlsConfig, tlsMode, err := clientTLS(c, sqlServerTLSCertFromParams)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		tlsConfig = tlsConfig.Clone()
+		// SQL Server expects one TCP segment per encrypted TDS packet, as msdsn.SetupTLS does.
+		tlsConfig.DynamicRecordSizingDisabled = true
+	}
 	cc.TLSConfig = tlsConfig
+	// The encrypt param takes precedence over the mode.
+	if encryption, ok := sqlServerEncryptions[tlsMode]; ok {
+		cc.Encryption = encryption
+	}
 	if dialTimeout > 0 {
 		cc.DialTimeout = dialTimeout
 	}
@@ -149,6 +218,38 @@ func ToSQLServerConfig(c *Config) (*msdsn.Config, error) {
 	return cc, nil
 }
 
+// sqlServerEncryptions maps the TLS modes to the encryption of msdsn,
+// prefer is mapped to off, with which the login packet is encrypted at least.
+var sqlServerEncryptions = map[string]msdsn.Encryption{
+	TLSModeDisable:    msdsn.EncryptionDisabled,
+	TLSModePrefer:     msdsn.EncryptionOff,
+	TLSModeRequire:    msdsn.EncryptionRequired,
+	TLSModeVerifyCA:   msdsn.EncryptionRequired,
+	TLSModeVerifyFull: msdsn.EncryptionRequired,
+}
+
+// sqlServerTLSCertFromParams converts encrypt, trustservercertificate, certificate, hostnameincertificate
+// and tlsmin to TLSCert, nil is returned if none of them is set. The server is verified fully unless it is trusted.
+func sqlServerTLSCertFromParams(params ConfigParams) *TLSCert {
+	p := mssqlparams.ConnParams
+	if !slices.ContainsFunc([]string{p.Encrypt, p.TrustServerCertificate, p.Certificate, p.HostNameInCertificate, p.TLSMin}, params.Exists) {
+		return nil
+	}
+	t := &TLSCert{
+		Mode:       TLSModeVerifyFull,
+		CAFile:     params.Get(p.Certificate),
+		ServerName: params.Get(p.HostNameInCertificate),
+		MinVersion: params.Get(p.TLSMin),
+	}
+	if trust, _ := strconv.ParseBool(params.Get(p.TrustServerCertificate)); trust {
+		t.Mode = TLSModeRequire
+	}
+	if strings.EqualFold(params.Get(p.Encrypt), "disable") {
+		t.Mode = TLSModeDisable
+	}
+	return t
+}
+
 func RawSQLServerDriver() driver.Driver {
 	return &mssql.Driver{}
 }
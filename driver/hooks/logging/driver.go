@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/blink-io/hypersql/driver/wrapper"
+)
+
+type (
+	resultConn struct {
+		wrapper.Conn
+	}
+
+	resultStmt struct {
+		wrapper.Stmt
+	}
+)
+
+// wrapDriver records the rows affected by the executed statements into the context of Hook.Before,
+// as sqlhooks does not pass the driver.Result to Hook.After.
+func wrapDriver(drv driver.Driver) driver.Driver {
+	return wrapper.WrapDriver(drv, func(open func() (driver.Conn, error)) (driver.Conn, error) {
+		conn, err := open()
+		if err != nil {
+			return nil, err
+		}
+		return &resultConn{Conn: wrapper.Conn{Conn: conn}}, nil
+	})
+}
+
+func (c *resultConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *resultConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &resultStmt{Stmt: wrapper.Stmt{Stmt: stmt, Conn: c}}, nil
+}
+
+func (c *resultConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.Conn.ExecContext(ctx, query, args)
+	if err == nil {
+		recordResult(ctx, res)
+	}
+	return res, err
+}
+
+func (s *resultStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	res, err := s.Stmt.ExecContext(ctx, args)
+	if err == nil {
+		recordResult(ctx, res)
+	}
+	return res, err
+}
+
+func recordResult(ctx context.Context, res driver.Result) {
+	s, ok := ctx.Value(stateKey{}).(*execState)
+	if !ok {
+		return
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		s.rowsAffected, s.hasRowsAffected = n, true
+	}
+}
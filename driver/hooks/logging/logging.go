@@ -6,6 +6,8 @@ import (
 	"github.com/qustavo/sqlhooks/v2"
 )
 
+// Func logs the statements with their raw args before they are executed,
+// see New for logging the duration and errors after execution.
 type Func func(format string, args ...any)
 
 var _ sqlhooks.Hooks = (Func)(nil)
@@ -19,6 +21,7 @@ func (f Func) After(ctx context.Context, query string, args ...any) (context.Con
 	return ctx, nil
 }
 
+// CtxFunc is Func with the context of statements.
 type CtxFunc func(ctx context.Context, format string, args ...any)
 
 var _ sqlhooks.Hooks = (CtxFunc)(nil)
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+type Option func(*Hook)
+
+// Logger sets the logger, it is slog.Default() by default.
+func Logger(logger *slog.Logger) Option {
+	return func(h *Hook) {
+		h.logger = logger
+	}
+}
+
+// SuccessLevel sets the level of succeeded statements, it is slog.LevelDebug by default.
+func SuccessLevel(level slog.Level) Option {
+	return func(h *Hook) {
+		h.successLevel = level
+	}
+}
+
+// SlowLevel sets the level of succeeded statements slower than SlowThreshold, it is slog.LevelWarn by default.
+func SlowLevel(level slog.Level) Option {
+	return func(h *Hook) {
+		h.slowLevel = level
+	}
+}
+
+// ErrorLevel sets the level of failed statements, it is slog.LevelError by default.
+func ErrorLevel(level slog.Level) Option {
+	return func(h *Hook) {
+		h.errorLevel = level
+	}
+}
+
+// SlowThreshold sets the duration from which a statement is slow, no statement is slow if it is not set.
+func SlowThreshold(d time.Duration) Option {
+	return func(h *Hook) {
+		h.slowThreshold = d
+	}
+}
+
+// MaxQueryLength truncates the logged queries longer than n bytes, it is DefaultMaxQueryLength by default.
+// The queries are not truncated if n is zero or negative.
+func MaxQueryLength(n int) Option {
+	return func(h *Hook) {
+		h.maxQueryLength = n
+	}
+}
+
+// RedactArgs redacts the args at the given positions, which start from 0.
+func RedactArgs(positions ...int) Option {
+	return func(h *Hook) {
+		h.redactPositions = append(h.redactPositions, positions...)
+	}
+}
+
+// RedactArgsMatching redacts all the args of the queries matching re, e.g. (?i)password.
+func RedactArgsMatching(re *regexp.Regexp) Option {
+	return func(h *Hook) {
+		h.redactQueries = append(h.redactQueries, re)
+	}
+}
+
+// RedactAllArgs redacts all the args.
+func RedactAllArgs() Option {
+	return func(h *Hook) {
+		h.redactAll = true
+	}
+}
+
+// SampleRate logs the given fraction of the succeeded statements which are not slow, it is 1 by default.
+// The failed and slow statements are always logged.
+func SampleRate(rate float64) Option {
+	return func(h *Hook) {
+		h.sampleRate = rate
+	}
+}
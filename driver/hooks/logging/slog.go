@@ -0,0 +1,167 @@
+package logging
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blink-io/hypersql"
+	"github.com/qustavo/sqlhooks/v2"
+)
+
+// DefaultMaxQueryLength is the length from which the logged queries are truncated when MaxQueryLength is not set.
+const DefaultMaxQueryLength = 2048
+
+const (
+	msgSucceeded = "SQL executed"
+	msgSlow      = "SQL executed slowly"
+	msgFailed    = "SQL failed"
+)
+
+var _ interface {
+	sqlhooks.Hooks
+	sqlhooks.OnErrorer
+} = (*Hook)(nil)
+
+type stateKey struct{}
+
+// execState is the state of a statement from Hook.Before to Hook.After.
+type execState struct {
+	start           time.Time
+	rowsAffected    int64
+	hasRowsAffected bool
+}
+
+// Hook logs the executed statements by slog after they finish, with the query, args and duration,
+// plus the error and its ErrName if they fail. The level depends on whether a statement succeeds, is slow or fails.
+// The rows affected by the succeeded Exec statements are logged too when the Hook is installed by Apply,
+// as the driver.Result is only available to the driver wrapper it adds.
+type Hook struct {
+	logger *slog.Logger
+
+	successLevel  slog.Level
+	slowLevel     slog.Level
+	errorLevel    slog.Level
+	slowThreshold time.Duration
+
+	maxQueryLength int
+
+	redactPositions []int
+	redactQueries   []*regexp.Regexp
+	redactAll       bool
+
+	sampleRate float64
+}
+
+func New(ops ...Option) *Hook {
+	h := &Hook{
+		successLevel:   slog.LevelDebug,
+		slowLevel:      slog.LevelWarn,
+		errorLevel:     slog.LevelError,
+		maxQueryLength: DefaultMaxQueryLength,
+		sampleRate:     1,
+	}
+	for _, o := range ops {
+		o(h)
+	}
+	if h.logger == nil {
+		h.logger = slog.Default()
+	}
+	return h
+}
+
+// Apply adds h to the driver hooks of c, with the driver wrapper which records the rows affected.
+func (h *Hook) Apply(c *hypersql.Config) {
+	c.DriverHooks = append(c.DriverHooks, h)
+	c.DriverWrappers = append(c.DriverWrappers, wrapDriver)
+}
+
+func (h *Hook) Before(ctx context.Context, query string, args ...any) (context.Context, error) {
+	return context.WithValue(ctx, stateKey{}, &execState{start: time.Now()}), nil
+}
+
+func (h *Hook) After(ctx context.Context, query string, args ...any) (context.Context, error) {
+	d := elapsed(ctx)
+	var attrs []slog.Attr
+	if s, ok := ctx.Value(stateKey{}).(*execState); ok && s.hasRowsAffected {
+		attrs = append(attrs, slog.Int64("rows_affected", s.rowsAffected))
+	}
+	if h.slowThreshold > 0 && d >= h.slowThreshold {
+		h.log(ctx, h.slowLevel, msgSlow, query, args, d, attrs...)
+	} else if h.sampled() {
+		h.log(ctx, h.successLevel, msgSucceeded, query, args, d, attrs...)
+	}
+	return ctx, nil
+}
+
+func (h *Hook) OnError(ctx context.Context, err error, query string, args ...any) error {
+	// database/sql retries the skipped statements in other ways, which are logged then.
+	if errors.Is(err, driver.ErrSkip) {
+		return err
+	}
+	h.log(ctx, h.errorLevel, msgFailed, query, args, elapsed(ctx),
+		slog.Any("error", err),
+		slog.String("err_name", hypersql.WrapError(err).Name().String()),
+	)
+	return err
+}
+
+func (h *Hook) log(ctx context.Context, level slog.Level, msg string, query string, args []any, d time.Duration, attrs ...slog.Attr) {
+	if !h.logger.Enabled(ctx, level) {
+		return
+	}
+	attrs = append([]slog.Attr{
+		slog.String("query", h.truncate(query)),
+		slog.Any("args", h.redact(query, args)),
+		slog.Duration("duration", d),
+	}, attrs...)
+	h.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+func (h *Hook) truncate(query string) string {
+	if h.maxQueryLength <= 0 || len(query) <= h.maxQueryLength {
+		return query
+	}
+	// Cut at a rune boundary so a multi-byte character is not split.
+	n := h.maxQueryLength
+	for n > 0 && !utf8.RuneStart(query[n]) {
+		n--
+	}
+	return query[:n] + "..."
+}
+
+// redact returns a copy of args whose redacted ones are replaced by hypersql.RedactedSecret.
+func (h *Hook) redact(query string, args []any) []any {
+	all := h.redactAll || slices.ContainsFunc(h.redactQueries, func(re *regexp.Regexp) bool {
+		return re.MatchString(query)
+	})
+	if !all && len(h.redactPositions) == 0 {
+		return args
+	}
+	redacted := make([]any, len(args))
+	for i, arg := range args {
+		if all || slices.Contains(h.redactPositions, i) {
+			redacted[i] = hypersql.RedactedSecret
+		} else {
+			redacted[i] = arg
+		}
+	}
+	return redacted
+}
+
+func (h *Hook) sampled() bool {
+	return h.sampleRate >= 1 || (h.sampleRate > 0 && rand.Float64() < h.sampleRate)
+}
+
+func elapsed(ctx context.Context) time.Duration {
+	if s, ok := ctx.Value(stateKey{}).(*execState); ok {
+		return time.Since(s.start)
+	}
+	return 0
+}
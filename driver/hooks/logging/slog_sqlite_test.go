@@ -0,0 +1,57 @@
+//go:build sqlite
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/blink-io/hypersql"
+	"github.com/blink-io/hypersql/sqlite"
+	sqliteparams "github.com/blink-io/hypersql/sqlite/params"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestHook_Apply(t *testing.T) {
+	ctx := context.Background()
+	l := new(testLog)
+	c := &hypersql.Config{
+		Dialect: hypersql.DialectSQLite,
+		Name:    "file:logging.db",
+		Params: hypersql.ConfigParams{
+			sqliteparams.ConnParams.Cache: sqlite.CacheShared,
+			sqliteparams.ConnParams.Mode:  sqlite.ModeMemory,
+		},
+	}
+	New(Logger(l.logger()), SuccessLevel(slog.LevelInfo)).Apply(c)
+
+	db, err := hypersql.NewSqlDB(c)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, "create table users (name text)")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "insert into users (name) values (?), (?)", "a", "b")
+	require.NoError(t, err)
+	stmt, err := db.PrepareContext(ctx, "update users set name = ? where name = ?")
+	require.NoError(t, err)
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, "c", "a")
+	require.NoError(t, err)
+	var n int
+	require.NoError(t, db.QueryRowContext(ctx, "select count(*) from users").Scan(&n))
+
+	rows := make(map[string]any)
+	for _, r := range l.records(t) {
+		if v, ok := r["rows_affected"]; ok {
+			rows[r["query"].(string)] = v
+		} else {
+			assert.Equal(t, "select count(*) from users", r["query"])
+		}
+	}
+	assert.Equal(t, float64(2), rows["insert into users (name) values (?), (?)"])
+	assert.Equal(t, float64(1), rows["update users set name = ? where name = ?"])
+}
@@ -0,0 +1,142 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blink-io/hypersql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLog struct {
+	buf bytes.Buffer
+}
+
+func (l *testLog) logger() *slog.Logger {
+	return slog.New(slog.NewJSONHandler(&l.buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func (l *testLog) records(t *testing.T) []map[string]any {
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(l.buf.String()), "\n") {
+		if len(line) == 0 {
+			continue
+		}
+		var r map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &r))
+		records = append(records, r)
+	}
+	l.buf.Reset()
+	return records
+}
+
+func execHook(h *Hook, query string, err error, args ...any) {
+	ctx, _ := h.Before(context.Background(), query, args...)
+	if err != nil {
+		_ = h.OnError(ctx, err, query, args...)
+	} else {
+		_, _ = h.After(ctx, query, args...)
+	}
+}
+
+func TestHook(t *testing.T) {
+	l := new(testLog)
+
+	t.Run("levels", func(t *testing.T) {
+		h := New(Logger(l.logger()), SuccessLevel(slog.LevelInfo))
+		execHook(h, "select 1", nil, 1)
+		uniqueErr := hypersql.ErrConstraintUnique.As("2067", "UNIQUE constraint failed", nil)
+		execHook(h, "insert into t values (?)", uniqueErr, 1)
+		execHook(h, "select 1", driver.ErrSkip)
+
+		records := l.records(t)
+		require.Len(t, records, 2)
+		assert.Equal(t, "INFO", records[0]["level"])
+		assert.Equal(t, msgSucceeded, records[0]["msg"])
+		assert.Equal(t, "select 1", records[0]["query"])
+		assert.Equal(t, []any{float64(1)}, records[0]["args"])
+		assert.Contains(t, records[0], "duration")
+		assert.NotContains(t, records[0], "error")
+
+		assert.Equal(t, "ERROR", records[1]["level"])
+		assert.Equal(t, msgFailed, records[1]["msg"])
+		assert.Equal(t, uniqueErr.Error(), records[1]["error"])
+		assert.Equal(t, hypersql.ErrNameConstraintUnique.String(), records[1]["err_name"])
+	})
+
+	t.Run("slow", func(t *testing.T) {
+		h := New(Logger(l.logger()), SlowThreshold(time.Millisecond), SlowLevel(slog.LevelInfo))
+		ctx, _ := h.Before(context.Background(), "select sleep(1)")
+		time.Sleep(2 * time.Millisecond)
+		_, _ = h.After(ctx, "select sleep(1)")
+
+		records := l.records(t)
+		require.Len(t, records, 1)
+		assert.Equal(t, "INFO", records[0]["level"])
+		assert.Equal(t, msgSlow, records[0]["msg"])
+	})
+
+	t.Run("level disabled", func(t *testing.T) {
+		h := New(Logger(l.logger()), ErrorLevel(slog.LevelDebug-1))
+		execHook(h, "select 1", errors.New("failed"))
+		assert.Empty(t, l.records(t))
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		h := New(Logger(l.logger()), MaxQueryLength(8))
+		execHook(h, "select * from users", nil)
+		h = New(Logger(l.logger()), MaxQueryLength(-1))
+		execHook(h, "select * from users", nil)
+		h = New(Logger(l.logger()), MaxQueryLength(0))
+		execHook(h, "select * from users", nil)
+		// "é" takes the 8th and 9th bytes.
+		h = New(Logger(l.logger()), MaxQueryLength(8))
+		execHook(h, "select é from users", nil)
+
+		records := l.records(t)
+		require.Len(t, records, 4)
+		assert.Equal(t, "select *...", records[0]["query"])
+		assert.Equal(t, "select * from users", records[1]["query"])
+		assert.Equal(t, "select * from users", records[2]["query"])
+		assert.Equal(t, "select ...", records[3]["query"])
+	})
+
+	t.Run("redact", func(t *testing.T) {
+		h := New(Logger(l.logger()),
+			RedactArgs(1),
+			RedactArgsMatching(regexp.MustCompile(`(?i)\bpassword\b`)),
+		)
+		execHook(h, "select * from users where name = ? and token = ?", nil, "a", "secret")
+		execHook(h, "update users set password = ? where name = ?", nil, "secret", "a")
+		execHook(h, "select 1", nil)
+		New(Logger(l.logger()), RedactAllArgs()).After(context.Background(), "select ?, ?", 1, 2)
+
+		records := l.records(t)
+		require.Len(t, records, 4)
+		assert.Equal(t, []any{"a", hypersql.RedactedSecret}, records[0]["args"])
+		assert.Equal(t, []any{hypersql.RedactedSecret, hypersql.RedactedSecret}, records[1]["args"])
+		assert.Equal(t, []any{}, records[2]["args"])
+		assert.Equal(t, []any{hypersql.RedactedSecret, hypersql.RedactedSecret}, records[3]["args"])
+	})
+
+	t.Run("sample", func(t *testing.T) {
+		h := New(Logger(l.logger()), SampleRate(0))
+		for range 10 {
+			execHook(h, "select 1", nil)
+		}
+		execHook(h, "select 1", errors.New("failed"))
+
+		records := l.records(t)
+		require.Len(t, records, 1)
+		assert.Equal(t, msgFailed, records[0]["msg"])
+	})
+}
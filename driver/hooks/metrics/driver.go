@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"github.com/blink-io/hypersql/driver/wrapper"
+)
+
+type (
+	metricsConn struct {
+		wrapper.Conn
+		c *Collector
+	}
+
+	metricsStmt struct {
+		wrapper.Stmt
+		c     *Collector
+		query string
+	}
+
+	metricsTx struct {
+		driver.Tx
+		c *Collector
+	}
+)
+
+// WrapDriver is a hypersql.DriverWrapper which records the metrics of drv.
+func (c *Collector) WrapDriver(drv driver.Driver) driver.Driver {
+	return wrapper.WrapDriver(drv, func(open func() (driver.Conn, error)) (driver.Conn, error) {
+		conn, err := open()
+		if err != nil {
+			return nil, err
+		}
+		return &metricsConn{Conn: wrapper.Conn{Conn: conn}, c: c}, nil
+	})
+}
+
+func (c *metricsConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *metricsConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsStmt{Stmt: wrapper.Stmt{Stmt: stmt, Conn: c}, c: c.c, query: query}, nil
+}
+
+func (c *metricsConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *metricsConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.Conn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsTx{Tx: tx, c: c.c}, nil
+}
+
+func (c *metricsConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := c.Conn.ExecContext(ctx, query, args)
+	c.c.observe(query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	c.c.observeResult(query, res)
+	return res, nil
+}
+
+func (c *metricsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := c.Conn.QueryContext(ctx, query, args)
+	c.c.observe(query, start, err)
+	return rows, err
+}
+
+func (s *metricsStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := s.Stmt.ExecContext(ctx, args)
+	s.c.observe(s.query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	s.c.observeResult(s.query, res)
+	return res, nil
+}
+
+func (s *metricsStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.Stmt.QueryContext(ctx, args)
+	s.c.observe(s.query, start, err)
+	return rows, err
+}
+
+func (t *metricsTx) Commit() error {
+	err := t.Tx.Commit()
+	if err == nil {
+		t.c.commits.Inc()
+	}
+	return err
+}
+
+func (t *metricsTx) Rollback() error {
+	err := t.Tx.Rollback()
+	if err == nil {
+		t.c.rollbacks.Inc()
+	}
+	return err
+}
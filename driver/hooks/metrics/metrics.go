@@ -0,0 +1,284 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"expvar"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/blink-io/hypersql"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DefaultNamespace is the prefix of the metric names when Namespace is not set.
+const DefaultNamespace = "hypersql"
+
+const (
+	LabelDB        = "db"
+	LabelDialect   = "dialect"
+	LabelOperation = "operation"
+	LabelError     = "error"
+)
+
+// OperationOther is the operation label of the statements which are not in Operations.
+const OperationOther = "OTHER"
+
+// Operations are the first keywords of statements used as the operation label,
+// the others are labeled as OperationOther to keep the cardinality low.
+var Operations = []string{
+	"SELECT", "INSERT", "UPDATE", "DELETE", "MERGE", "UPSERT", "REPLACE", "WITH", "VALUES",
+	"CALL", "EXEC", "EXECUTE", "BEGIN", "COMMIT", "ROLLBACK", "SAVEPOINT", "RELEASE",
+	"CREATE", "ALTER", "DROP", "TRUNCATE", "SET", "SHOW", "EXPLAIN", "PRAGMA", "LOCK", "COPY",
+}
+
+var ErrExpvarPublished = errors.New("expvar is already published")
+
+var (
+	expvarMu sync.Mutex
+	// expvars holds the collectors of the published names.
+	expvars = make(map[string]*atomic.Pointer[Collector])
+)
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+type (
+	// Collector collects the pool stats, query durations, rows affected and transactions of a DB,
+	// and implements prometheus.Collector. The metrics are labeled by the name and dialect of DBInfo,
+	// so a Collector is created and registered per DB.
+	Collector struct {
+		info      hypersql.DBInfo
+		namespace string
+		buckets   []float64
+
+		db atomic.Pointer[sql.DB]
+
+		duration  *prometheus.HistogramVec
+		rows      *prometheus.CounterVec
+		commits   prometheus.Counter
+		rollbacks prometheus.Counter
+		stats     []dbStat
+	}
+
+	// dbStat is a metric of sql.DBStats.
+	dbStat struct {
+		desc      *prometheus.Desc
+		valueType prometheus.ValueType
+		value     func(sql.DBStats) float64
+	}
+)
+
+func New(info hypersql.DBInfo, ops ...Option) *Collector {
+	c := &Collector{
+		info:      info,
+		namespace: DefaultNamespace,
+		buckets:   prometheus.DefBuckets,
+	}
+	for _, o := range ops {
+		o(c)
+	}
+
+	labels := prometheus.Labels{LabelDB: info.Name, LabelDialect: info.Dialect}
+	c.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   c.namespace,
+		Name:        "query_duration_seconds",
+		Help:        "Duration of queries and executions, the error label is the ErrName of failed ones.",
+		ConstLabels: labels,
+		Buckets:     c.buckets,
+	}, []string{LabelOperation, LabelError})
+	c.rows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   c.namespace,
+		Name:        "rows_affected_total",
+		Help:        "Number of rows affected by executions.",
+		ConstLabels: labels,
+	}, []string{LabelOperation})
+	c.commits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   c.namespace,
+		Name:        "tx_commits_total",
+		Help:        "Number of committed transactions.",
+		ConstLabels: labels,
+	})
+	c.rollbacks = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   c.namespace,
+		Name:        "tx_rollbacks_total",
+		Help:        "Number of rolled back transactions.",
+		ConstLabels: labels,
+	})
+
+	stat := func(name, help string, valueType prometheus.ValueType, value func(sql.DBStats) float64) dbStat {
+		desc := prometheus.NewDesc(prometheus.BuildFQName(c.namespace, "", name), help, nil, labels)
+		return dbStat{desc: desc, valueType: valueType, value: value}
+	}
+	c.stats = []dbStat{
+		stat("max_open_connections", "Maximum number of open connections.", prometheus.GaugeValue,
+			func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }),
+		stat("open_connections", "Number of established connections.", prometheus.GaugeValue,
+			func(s sql.DBStats) float64 { return float64(s.OpenConnections) }),
+		stat("in_use_connections", "Number of connections in use.", prometheus.GaugeValue,
+			func(s sql.DBStats) float64 { return float64(s.InUse) }),
+		stat("idle_connections", "Number of idle connections.", prometheus.GaugeValue,
+			func(s sql.DBStats) float64 { return float64(s.Idle) }),
+		stat("wait_count_total", "Number of connections waited for.", prometheus.CounterValue,
+			func(s sql.DBStats) float64 { return float64(s.WaitCount) }),
+		stat("wait_duration_seconds_total", "Time blocked waiting for connections.", prometheus.CounterValue,
+			func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }),
+		stat("max_idle_closed_total", "Number of connections closed due to SetMaxIdleConns.", prometheus.CounterValue,
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }),
+		stat("max_idle_time_closed_total", "Number of connections closed due to SetConnMaxIdleTime.", prometheus.CounterValue,
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }),
+		stat("max_lifetime_closed_total", "Number of connections closed due to SetConnMaxLifetime.", prometheus.CounterValue,
+			func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }),
+	}
+	return c
+}
+
+// Apply adds the driver wrapper of c to cfg, and sets the created sql.DB for the pool stats.
+func (c *Collector) Apply(cfg *hypersql.Config) {
+	cfg.DriverWrappers = append(cfg.DriverWrappers, c.WrapDriver)
+	cfg.AfterHandlers = append(cfg.AfterHandlers, func(ctx context.Context, db *sql.DB) error {
+		c.SetDB(db)
+		return nil
+	})
+}
+
+// SetDB sets the sql.DB whose stats are collected, no pool stats are collected before it is set.
+func (c *Collector) SetDB(db *sql.DB) {
+	c.db.Store(db)
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.duration.Describe(ch)
+	c.rows.Describe(ch)
+	c.commits.Describe(ch)
+	c.rollbacks.Describe(ch)
+	for _, s := range c.stats {
+		ch <- s.desc
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.duration.Collect(ch)
+	c.rows.Collect(ch)
+	c.commits.Collect(ch)
+	c.rollbacks.Collect(ch)
+	if db := c.db.Load(); db != nil {
+		stats := db.Stats()
+		for _, s := range c.stats {
+			ch <- prometheus.MustNewConstMetric(s.desc, s.valueType, s.value(stats))
+		}
+	}
+}
+
+// Expvar returns a view of the metrics for expvar. Metrics are keyed by their names without the namespace,
+// and the ones with variable labels are keyed by their sorted labels, e.g. error=unique_constraint,operation=INSERT.
+func (c *Collector) Expvar() expvar.Var {
+	return expvar.Func(c.expvarView)
+}
+
+// PublishExpvar publishes Expvar as <namespace>.<DBInfo name>.
+// Expvar can't be unpublished, so publishing a name again, e.g. when the DB is reopened,
+// replaces the collector of it. ErrExpvarPublished is returned if the name is taken by another variable.
+func (c *Collector) PublishExpvar() error {
+	name := c.namespace + "." + c.info.Name
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if p, ok := expvars[name]; ok {
+		p.Store(c)
+		return nil
+	}
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("%w: %s", ErrExpvarPublished, name)
+	}
+	p := new(atomic.Pointer[Collector])
+	p.Store(c)
+	expvars[name] = p
+	expvar.Publish(name, expvar.Func(func() any {
+		return p.Load().expvarView()
+	}))
+	return nil
+}
+
+func (c *Collector) expvarView() any {
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(c); err != nil {
+		return err.Error()
+	}
+	mfs, err := reg.Gather()
+	if err != nil {
+		return err.Error()
+	}
+
+	view := map[string]any{
+		LabelDB:      c.info.Name,
+		LabelDialect: c.info.Dialect,
+	}
+	for _, mf := range mfs {
+		values := make(map[string]any, len(mf.GetMetric()))
+		for _, m := range mf.GetMetric() {
+			values[expvarLabels(m)] = expvarValue(m)
+		}
+		name := strings.TrimPrefix(mf.GetName(), c.namespace+"_")
+		if v, ok := values[""]; ok && len(values) == 1 {
+			view[name] = v
+		} else {
+			view[name] = values
+		}
+	}
+	return view
+}
+
+// expvarLabels joins the non-empty variable labels of m.
+func expvarLabels(m *dto.Metric) string {
+	var labels []string
+	for _, l := range m.GetLabel() {
+		if name := l.GetName(); name != LabelDB && name != LabelDialect && len(l.GetValue()) > 0 {
+			labels = append(labels, name+"="+l.GetValue())
+		}
+	}
+	return strings.Join(labels, ",")
+}
+
+func expvarValue(m *dto.Metric) any {
+	switch {
+	case m.GetHistogram() != nil:
+		h := m.GetHistogram()
+		return map[string]any{"count": h.GetSampleCount(), "sum": h.GetSampleSum()}
+	case m.GetCounter() != nil:
+		return m.GetCounter().GetValue()
+	default:
+		return m.GetGauge().GetValue()
+	}
+}
+
+// observe records the duration of query since start, the skipped calls are retried by database/sql in other ways.
+func (c *Collector) observe(query string, start time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	var name string
+	if err != nil {
+		name = hypersql.WrapError(err).Name().String()
+	}
+	c.duration.WithLabelValues(Operation(query), name).Observe(time.Since(start).Seconds())
+}
+
+func (c *Collector) observeResult(query string, res driver.Result) {
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		c.rows.WithLabelValues(Operation(query)).Add(float64(n))
+	}
+}
+
+// Operation returns the operation label of query, which is its first keyword in upper case if it is in Operations.
+func Operation(query string) string {
+	if op := hypersql.QueryKeyword(query); slices.Contains(Operations, op) {
+		return op
+	}
+	return OperationOther
+}
@@ -0,0 +1,111 @@
+//go:build sqlite
+
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/blink-io/hypersql"
+	"github.com/blink-io/hypersql/sqlite"
+	sqliteparams "github.com/blink-io/hypersql/sqlite/params"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+	c := &hypersql.Config{
+		Dialect: hypersql.DialectSQLite,
+		Name:    "file:metrics.db",
+		Params: hypersql.ConfigParams{
+			sqliteparams.ConnParams.Cache: sqlite.CacheShared,
+			sqliteparams.ConnParams.Mode:  sqlite.ModeMemory,
+		},
+	}
+	mc := New(c.DBInfo())
+	mc.Apply(c)
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(mc))
+
+	db, err := hypersql.NewSqlDB(c)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, "create table users (name text primary key)")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "insert into users (name) values ('a'), ('b')")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "insert into users (name) values ('a')")
+	require.True(t, hypersql.IsErrConstraintUnique(err))
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(ctx, "update users set name = name || '1'")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	tx, err = db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	stmt, err := db.PrepareContext(ctx, "select name from users where name = ?")
+	require.NoError(t, err)
+	rows, err := stmt.QueryContext(ctx, "a1")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+	require.NoError(t, stmt.Close())
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.rows.WithLabelValues("INSERT")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(mc.rows.WithLabelValues("UPDATE")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.commits))
+	assert.Equal(t, float64(1), testutil.ToFloat64(mc.rollbacks))
+
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+	durations := make(map[string]uint64)
+	names := make(map[string]bool)
+	for _, mf := range mfs {
+		names[mf.GetName()] = true
+		if mf.GetName() != "hypersql_query_duration_seconds" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			assert.Equal(t, "file:metrics.db", labels[LabelDB])
+			durations[labels[LabelOperation]+"/"+labels[LabelError]] = m.GetHistogram().GetSampleCount()
+		}
+	}
+	assert.Equal(t, uint64(1), durations["INSERT/"])
+	assert.Equal(t, uint64(1), durations["INSERT/"+hypersql.ErrNameConstraintUnique.String()])
+	assert.Equal(t, uint64(1), durations["UPDATE/"])
+	assert.Equal(t, uint64(1), durations["SELECT/"])
+	assert.True(t, names["hypersql_open_connections"])
+	assert.True(t, names["hypersql_wait_duration_seconds_total"])
+
+	// Publishing again replaces the collector of the name, e.g. the one of a reopened DB.
+	require.NoError(t, New(c.DBInfo()).PublishExpvar())
+	var view map[string]any
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("hypersql.file:metrics.db").String()), &view))
+	assert.Equal(t, float64(0), view["tx_commits_total"])
+	require.NoError(t, mc.PublishExpvar())
+	if expvar.Get("hypersql.taken") == nil {
+		expvar.NewInt("hypersql.taken")
+	}
+	require.ErrorIs(t, New(hypersql.DBInfo{Name: "taken"}).PublishExpvar(), ErrExpvarPublished)
+	view = nil
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("hypersql.file:metrics.db").String()), &view))
+	assert.Equal(t, "file:metrics.db", view[LabelDB])
+	assert.Equal(t, float64(1), view["tx_commits_total"])
+	assert.Contains(t, view, "open_connections")
+	durationView, ok := view["query_duration_seconds"].(map[string]any)
+	require.True(t, ok)
+	assert.Contains(t, durationView, "error="+hypersql.ErrNameConstraintUnique.String()+",operation=INSERT")
+}
@@ -0,0 +1,16 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperation(t *testing.T) {
+	assert.Equal(t, "SELECT", Operation("  select 1"))
+	assert.Equal(t, "SELECT", Operation("(SELECT 1) UNION (SELECT 2)"))
+	assert.Equal(t, "INSERT", Operation("insert into t values (1)"))
+	assert.Equal(t, OperationOther, Operation("vacuum"))
+	assert.Equal(t, "SELECT", Operation("/* comment */ select 1"))
+	assert.Equal(t, OperationOther, Operation(""))
+}
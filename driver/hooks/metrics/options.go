@@ -0,0 +1,17 @@
+package metrics
+
+type Option func(*Collector)
+
+// Namespace sets the prefix of the metric names, it is hypersql by default.
+func Namespace(namespace string) Option {
+	return func(c *Collector) {
+		c.namespace = namespace
+	}
+}
+
+// Buckets sets the buckets in seconds of the query duration histogram, they are prometheus.DefBuckets by default.
+func Buckets(buckets ...float64) Option {
+	return func(c *Collector) {
+		c.buckets = buckets
+	}
+}
@@ -5,14 +5,16 @@ import (
 )
 
 var (
-	WithAttributes                 = otelsql.WithAttributes
-	WithAttributesGetter           = otelsql.WithAttributesGetter
-	WithInstrumentAttributesGetter = otelsql.WithInstrumentAttributesGetter
-	WithMeterProvider              = otelsql.WithMeterProvider
-	WithSQLCommenter               = otelsql.WithSQLCommenter
-	WithSpanNameFormatter          = otelsql.WithSpanNameFormatter
-	WithSpanOptions                = otelsql.WithSpanOptions
-	WithTracerProvider             = otelsql.WithTracerProvider
+	WithAttributes                      = otelsql.WithAttributes
+	WithAttributesGetter                = otelsql.WithAttributesGetter
+	WithInstrumentAttributesGetter      = otelsql.WithInstrumentAttributesGetter
+	WithInstrumentErrorAttributesGetter = otelsql.WithInstrumentErrorAttributesGetter
+	WithDisableSkipErrMeasurement       = otelsql.WithDisableSkipErrMeasurement
+	WithMeterProvider                   = otelsql.WithMeterProvider
+	WithSQLCommenter                    = otelsql.WithSQLCommenter
+	WithSpanNameFormatter               = otelsql.WithSpanNameFormatter
+	WithSpanOptions                     = otelsql.WithSpanOptions
+	WithTracerProvider                  = otelsql.WithTracerProvider
 )
 
 type (
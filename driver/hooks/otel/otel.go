@@ -2,20 +2,121 @@ package otel
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/XSAM/otelsql"
+	"github.com/blink-io/hypersql"
+	"github.com/blink-io/hypersql/driver/wrapper"
+	"github.com/qustavo/sqlhooks/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconvlegacy "go.opentelemetry.io/otel/semconv/v1.24.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrNameKey is the attribute of the classified hypersql.ErrName, it is set on spans and metrics.
+// The error.type of metrics is set by otelsql to the Go type of the error, so it is kept apart.
+const ErrNameKey = attribute.Key("hypersql.error.name")
+
+// errTypeOther is the error.type when the error is not classified.
+const errTypeOther = "_OTHER"
+
+var (
+	_ sqlhooks.Hooks     = errorHook{}
+	_ sqlhooks.OnErrorer = errorHook{}
 )
 
+// dbSystems are the db.system.name and the legacy db.system attributes of dialects.
+var dbSystems = map[string][]attribute.KeyValue{
+	hypersql.DialectPostgres:  {semconv.DBSystemNamePostgreSQL, semconvlegacy.DBSystemPostgreSQL},
+	hypersql.DialectMySQL:     {semconv.DBSystemNameMySQL, semconvlegacy.DBSystemMySQL},
+	hypersql.DialectSQLServer: {semconv.DBSystemNameMicrosoftSQLServer, semconvlegacy.DBSystemMSSQL},
+	hypersql.DialectSQLite:    {semconv.DBSystemNameSqlite, semconvlegacy.DBSystemSqlite},
+	hypersql.DialectSQLite3:   {semconv.DBSystemNameSqlite, semconvlegacy.DBSystemSqlite},
+}
+
+// Hook instruments a Config with otelsql. Spans and the db.client.operation.duration
+// histogram are created by the driver wrapper, and the pool metrics are registered
+// once sql.DB is created. It is not a sqlhooks.Hooks, use Apply to install it into Config.
+//
+// As otelsql, the stable semantic conventions, e.g. db.query.text and db.client.operation.duration,
+// are emitted when OTEL_SEMCONV_STABILITY_OPT_IN contains database or database/dup,
+// otherwise the legacy db.statement and db.sql.latency are.
 type Hook struct {
+	ops []Option
 }
 
 func New(ops ...Option) *Hook {
-	h := &Hook{}
+	h := &Hook{
+		ops: ops,
+	}
 	return h
 }
 
-func (h *Hook) Before(ctx context.Context, query string, args ...any) (context.Context, error) {
+// Apply adds the driver wrapper and the pool metrics registration of h to c.
+func (h *Hook) Apply(c *hypersql.Config) {
+	ops := h.options(c)
+	c.DriverWrappers = append(c.DriverWrappers, func(drv driver.Driver) driver.Driver {
+		// errorHook is wrapped inside otelsql, so the span of otelsql is in the context of OnError.
+		return otelsql.WrapDriver(wrapper.WrapHooks(drv, errorHook{}), ops...)
+	})
+	c.AfterHandlers = append(c.AfterHandlers, func(ctx context.Context, db *sql.DB) error {
+		return otelsql.RegisterDBStatsMetrics(db, ops...)
+	})
+}
+
+// errorHook marks the spans of the failed statements, it is installed by Hook.Apply.
+type errorHook struct{}
+
+func (errorHook) Before(ctx context.Context, query string, args ...any) (context.Context, error) {
 	return ctx, nil
 }
 
-func (h *Hook) After(ctx context.Context, query string, args ...any) (context.Context, error) {
+func (errorHook) After(ctx context.Context, query string, args ...any) (context.Context, error) {
 	return ctx, nil
 }
+
+// OnError marks the current span as failed with the classified ErrName of err.
+func (errorHook) OnError(ctx context.Context, err error, query string, args ...any) error {
+	if errors.Is(err, driver.ErrSkip) {
+		return err
+	}
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return err
+	}
+	name := errName(err)
+	errType := name
+	if name == hypersql.ErrNameUnsupported.String() {
+		errType = errTypeOther
+	}
+	span.SetAttributes(semconv.ErrorTypeKey.String(errType), ErrNameKey.String(name))
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+// options returns the default options of c followed by the ones of h, so the latter take precedence.
+func (h *Hook) options(c *hypersql.Config) []Option {
+	attrs := []attribute.KeyValue{semconv.DBNamespace(c.Name)}
+	attrs = append(attrs, dbSystems[hypersql.GetFormalDialect(c.Dialect)]...)
+	ops := []Option{
+		WithAttributes(attrs...),
+		WithAttributesGetter(func(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) []attribute.KeyValue {
+			if op := hypersql.QueryKeyword(query); len(op) > 0 {
+				return []attribute.KeyValue{semconv.DBOperationName(op)}
+			}
+			return nil
+		}),
+		WithInstrumentErrorAttributesGetter(func(err error) []attribute.KeyValue {
+			return []attribute.KeyValue{ErrNameKey.String(errName(err))}
+		}),
+	}
+	return append(ops, h.ops...)
+}
+
+func errName(err error) string {
+	return hypersql.WrapError(err).Name().String()
+}
@@ -0,0 +1,103 @@
+//go:build sqlite
+
+package otel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blink-io/hypersql"
+	"github.com/blink-io/hypersql/sqlite"
+	sqliteparams "github.com/blink-io/hypersql/sqlite/params"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	_ "modernc.org/sqlite"
+)
+
+func TestHook_Apply(t *testing.T) {
+	t.Setenv("OTEL_SEMCONV_STABILITY_OPT_IN", "database/dup")
+	ctx := context.Background()
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+
+	c := &hypersql.Config{
+		Dialect: hypersql.DialectSQLite,
+		Name:    "file:otel.db",
+		Params: hypersql.ConfigParams{
+			sqliteparams.ConnParams.Cache: sqlite.CacheShared,
+			sqliteparams.ConnParams.Mode:  sqlite.ModeMemory,
+		},
+	}
+	New(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	).Apply(c)
+
+	db, err := hypersql.NewSqlDB(c)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, "create table users (name text primary key)")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "insert into users (name) values ('a')")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "insert into users (name) values ('a')")
+	require.True(t, hypersql.IsErrConstraintUnique(err))
+
+	var failed, ok bool
+	for _, s := range spans.Ended() {
+		attrs := attributeMap(s.Attributes())
+		if attrs["db.query.text"] != "insert into users (name) values ('a')" {
+			continue
+		}
+		assert.Equal(t, "sqlite", attrs["db.system"])
+		assert.Equal(t, "sqlite", attrs["db.system.name"])
+		assert.Equal(t, "file:otel.db", attrs["db.namespace"])
+		assert.Equal(t, "INSERT", attrs["db.operation.name"])
+		if s.Status().Code == codes.Error {
+			failed = true
+			assert.Equal(t, hypersql.ErrNameConstraintUnique.String(), attrs["error.type"])
+			assert.Equal(t, hypersql.ErrNameConstraintUnique.String(), attrs[ErrNameKey])
+		} else {
+			ok = true
+		}
+	}
+	assert.True(t, failed, "no failed insert span")
+	assert.True(t, ok, "no succeeded insert span")
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	metrics := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	assert.Contains(t, metrics, "db.sql.connection.open")
+	assert.Contains(t, metrics, "db.sql.latency")
+	require.Contains(t, metrics, "db.client.operation.duration")
+	hist, isHist := metrics["db.client.operation.duration"].(metricdata.Histogram[float64])
+	require.True(t, isHist)
+	var errNames []string
+	for _, dp := range hist.DataPoints {
+		assert.Equal(t, "file:otel.db", attributeMap(dp.Attributes.ToSlice())["db.namespace"])
+		if v, found := dp.Attributes.Value(ErrNameKey); found {
+			errNames = append(errNames, v.AsString())
+		}
+	}
+	assert.Contains(t, errNames, hypersql.ErrNameConstraintUnique.String())
+}
+
+func attributeMap(kvs []attribute.KeyValue) map[attribute.Key]string {
+	m := make(map[attribute.Key]string, len(kvs))
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value.Emit()
+	}
+	return m
+}
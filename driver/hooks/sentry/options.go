@@ -1,15 +1,52 @@
 package sentry
 
-import "github.com/getsentry/sentry-go"
+import (
+	"github.com/blink-io/hypersql"
+	"github.com/getsentry/sentry-go"
+)
 
 type Option func(*hook)
 
+// Hub sets the hub used when there is none in the context, it is sentry.CurrentHub() by default.
 func Hub(hub *sentry.Hub) Option {
 	return func(h *hook) {
-		if h == nil {
-			h.hub = sentry.CurrentHub()
-		} else {
-			h.hub = hub
-		}
+		h.hub = hub
+	}
+}
+
+// Info sets the dialect and db name tags of the events and spans.
+func Info(info hypersql.DBInfo) Option {
+	return func(h *hook) {
+		h.info = info
+	}
+}
+
+// IgnoreErrors skips capturing the errors with the given names, hypersql.ErrNameNoRows is always ignored.
+func IgnoreErrors(names ...hypersql.ErrName) Option {
+	return func(h *hook) {
+		h.ignored = append(h.ignored, names...)
+	}
+}
+
+// ScrubArgs sets the function to scrub the args sent to Sentry.
+// All args are replaced by hypersql.RedactedSecret by default.
+func ScrubArgs(scrub func(query string, args []any) []any) Option {
+	return func(h *hook) {
+		h.scrub = scrub
+	}
+}
+
+// Breadcrumbs enables the query breadcrumbs, it is enabled by default.
+func Breadcrumbs(enabled bool) Option {
+	return func(h *hook) {
+		h.breadcrumbs = enabled
+	}
+}
+
+// Spans enables the performance spans of queries, it is enabled by default.
+// The spans are only started under a span in the context, e.g. a transaction.
+func Spans(enabled bool) Option {
+	return func(h *hook) {
+		h.spans = enabled
 	}
 }
@@ -2,13 +2,40 @@ package sentry
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"time"
 
+	"github.com/blink-io/hypersql"
 	"github.com/getsentry/sentry-go"
 	"github.com/qustavo/sqlhooks/v2"
 )
 
+// SpanOp is the operation of the query spans.
+const SpanOp = "db.sql.query"
+
+const (
+	TagDialect = "db.system"
+	TagDBName  = "db.name"
+	TagErrName = "db.err_name"
+)
+
 type hook struct {
-	hub *sentry.Hub
+	hub         *sentry.Hub
+	info        hypersql.DBInfo
+	ignored     []hypersql.ErrName
+	scrub       func(query string, args []any) []any
+	breadcrumbs bool
+	spans       bool
+}
+
+// queryKey holds the queryState of a query in its context.
+type queryKey struct{}
+
+type queryState struct {
+	start time.Time
+	span  *sentry.Span
 }
 
 var _ interface {
@@ -17,7 +44,12 @@ var _ interface {
 } = (*hook)(nil)
 
 func New(ops ...Option) (sqlhooks.Hooks, error) {
-	h := new(hook)
+	h := &hook{
+		ignored:     []hypersql.ErrName{hypersql.ErrNameNoRows},
+		scrub:       redactArgs,
+		breadcrumbs: true,
+		spans:       true,
+	}
 	for _, o := range ops {
 		o(h)
 	}
@@ -27,17 +59,112 @@ func New(ops ...Option) (sqlhooks.Hooks, error) {
 	return h, nil
 }
 
+func (h *hook) Before(ctx context.Context, query string, args ...any) (context.Context, error) {
+	st := &queryState{start: time.Now()}
+	if h.spans && sentry.SpanFromContext(ctx) != nil {
+		st.span = sentry.StartSpan(ctx, SpanOp, sentry.WithDescription(query))
+		h.setSpanData(st.span)
+		ctx = st.span.Context()
+	}
+	return context.WithValue(ctx, queryKey{}, st), nil
+}
+
+func (h *hook) After(ctx context.Context, query string, args ...any) (context.Context, error) {
+	st, _ := ctx.Value(queryKey{}).(*queryState)
+	if st != nil && st.span != nil {
+		st.span.Status = sentry.SpanStatusOK
+		st.span.Finish()
+	}
+	h.addBreadcrumb(ctx, st, sentry.LevelInfo, query, args, "")
+	return ctx, nil
+}
+
 func (h *hook) OnError(ctx context.Context, err error, query string, args ...any) error {
-	if err != nil {
-		h.hub.CaptureException(err)
+	// database/sql retries the skipped statements in other ways,
+	// the unfinished span is dropped by Sentry.
+	if err == nil || errors.Is(err, driver.ErrSkip) {
+		return err
+	}
+	errName := hypersql.WrapError(err).Name()
+	st, _ := ctx.Value(queryKey{}).(*queryState)
+	if st != nil && st.span != nil {
+		st.span.Status = sentry.SpanStatusInternalError
+		st.span.SetTag(TagErrName, errName.String())
+		st.span.Finish()
+	}
+	h.addBreadcrumb(ctx, st, sentry.LevelError, query, args, errName)
+	if h.isIgnored(err, errName) {
+		return err
 	}
+
+	hub := h.hubFrom(ctx).Clone()
+	scope := hub.Scope()
+	scope.SetTags(h.tags())
+	scope.SetTag(TagErrName, errName.String())
+	scope.SetContext("query", sentry.Context{
+		"query": query,
+		"args":  h.scrub(query, args),
+	})
+	hub.CaptureException(err)
 	return err
 }
 
-func (h *hook) Before(ctx context.Context, query string, args ...any) (context.Context, error) {
-	return ctx, nil
+// hubFrom returns the hub in ctx if there is one, or the hub of h.
+func (h *hook) hubFrom(ctx context.Context) *sentry.Hub {
+	if hub := sentry.GetHubFromContext(ctx); hub != nil {
+		return hub
+	}
+	return h.hub
 }
 
-func (h *hook) After(ctx context.Context, query string, args ...any) (context.Context, error) {
-	return ctx, nil
+func (h *hook) isIgnored(err error, errName hypersql.ErrName) bool {
+	return hypersql.IsErrNoRows(err) || slices.Contains(h.ignored, errName)
+}
+
+func (h *hook) addBreadcrumb(ctx context.Context, st *queryState, level sentry.Level, query string, args []any, errName hypersql.ErrName) {
+	if !h.breadcrumbs {
+		return
+	}
+	data := map[string]any{
+		"args": h.scrub(query, args),
+	}
+	if st != nil {
+		data["duration_ms"] = time.Since(st.start).Milliseconds()
+	}
+	if len(errName) > 0 {
+		data["err_name"] = errName.String()
+	}
+	h.hubFrom(ctx).AddBreadcrumb(&sentry.Breadcrumb{
+		Type:     "query",
+		Category: "query",
+		Message:  query,
+		Level:    level,
+		Data:     data,
+	}, nil)
+}
+
+func (h *hook) setSpanData(span *sentry.Span) {
+	for k, v := range h.tags() {
+		span.SetTag(k, v)
+		span.SetData(k, v)
+	}
+}
+
+func (h *hook) tags() map[string]string {
+	tags := make(map[string]string, 2)
+	if len(h.info.Dialect) > 0 {
+		tags[TagDialect] = h.info.Dialect
+	}
+	if len(h.info.Name) > 0 {
+		tags[TagDBName] = h.info.Name
+	}
+	return tags
+}
+
+func redactArgs(query string, args []any) []any {
+	redacted := make([]any, len(args))
+	for i := range args {
+		redacted[i] = hypersql.RedactedSecret
+	}
+	return redacted
 }
@@ -0,0 +1,175 @@
+package sentry
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blink-io/hypersql"
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTransport struct {
+	mu     sync.Mutex
+	events []*sentry.Event
+}
+
+func (t *mockTransport) Configure(sentry.ClientOptions) {}
+
+func (t *mockTransport) SendEvent(event *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+}
+
+func (t *mockTransport) Flush(time.Duration) bool { return true }
+
+func (t *mockTransport) FlushWithContext(context.Context) bool { return true }
+
+func (t *mockTransport) Close() {}
+
+func (t *mockTransport) Events() []*sentry.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.events
+}
+
+func newTestHub(t *testing.T) (*sentry.Hub, *mockTransport) {
+	tr := new(mockTransport)
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:              "https://public@sentry.example.com/1",
+		Transport:        tr,
+		EnableTracing:    true,
+		TracesSampleRate: 1,
+	})
+	require.NoError(t, err)
+	return sentry.NewHub(client, sentry.NewScope()), tr
+}
+
+var testInfo = hypersql.DBInfo{Name: "app", Dialect: hypersql.DialectPostgres}
+
+func runHook(h interface {
+	Before(context.Context, string, ...any) (context.Context, error)
+	After(context.Context, string, ...any) (context.Context, error)
+	OnError(context.Context, error, string, ...any) error
+}, ctx context.Context, query string, err error, args ...any) {
+	ctx, _ = h.Before(ctx, query, args...)
+	if err != nil {
+		_ = h.OnError(ctx, err, query, args...)
+	} else {
+		_, _ = h.After(ctx, query, args...)
+	}
+}
+
+func TestHook_Errors(t *testing.T) {
+	hub, tr := newTestHub(t)
+	sh, err := New(Hub(hub), Info(testInfo), IgnoreErrors(hypersql.ErrNameConstraintCheck))
+	require.NoError(t, err)
+	h := sh.(*hook)
+	ctx := context.Background()
+
+	uniqueErr := hypersql.ErrConstraintUnique.As("23505", "duplicate key", nil)
+	runHook(h, ctx, "insert into users (name) values ($1)", uniqueErr, "alice")
+	runHook(h, ctx, "select name from users", sql.ErrNoRows)
+	runHook(h, ctx, "insert into users (age) values ($1)", hypersql.ErrConstraintCheck.As("23514", "check", nil), -1)
+	runHook(h, ctx, "select 1", driver.ErrSkip)
+
+	events := tr.Events()
+	require.Len(t, events, 1)
+	e := events[0]
+	require.Len(t, e.Exception, 1)
+	assert.Equal(t, uniqueErr.Error(), e.Exception[0].Value)
+	assert.Equal(t, "postgres", e.Tags[TagDialect])
+	assert.Equal(t, "app", e.Tags[TagDBName])
+	assert.Equal(t, hypersql.ErrNameConstraintUnique.String(), e.Tags[TagErrName])
+	assert.Equal(t, "insert into users (name) values ($1)", e.Contexts["query"]["query"])
+	assert.Equal(t, []any{hypersql.RedactedSecret}, e.Contexts["query"]["args"])
+
+	// The tags of an event are not left on the hub.
+	hub.CaptureMessage("after")
+	events = tr.Events()
+	require.Len(t, events, 2)
+	assert.NotContains(t, events[1].Tags, TagErrName)
+}
+
+func TestHook_HubFromContext(t *testing.T) {
+	hub, tr := newTestHub(t)
+	ctxHub, ctxTr := newTestHub(t)
+	h, err := New(Hub(hub))
+	require.NoError(t, err)
+
+	ctx := sentry.SetHubOnContext(context.Background(), ctxHub)
+	runHook(h.(*hook), ctx, "select 1", errors.New("failed"))
+	assert.Empty(t, tr.Events())
+	assert.Len(t, ctxTr.Events(), 1)
+}
+
+func TestHook_Breadcrumbs(t *testing.T) {
+	hub, tr := newTestHub(t)
+	h, err := New(Hub(hub), ScrubArgs(func(query string, args []any) []any {
+		return append(args[:0:0], args[0], hypersql.RedactedSecret)
+	}))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	runHook(h.(*hook), ctx, "select * from users where name = ? and token = ?", nil, "alice", "secret")
+	runHook(h.(*hook), ctx, "select * from users where name = ? and token = ?", sql.ErrNoRows, "bob", "secret")
+	hub.CaptureMessage("done")
+
+	events := tr.Events()
+	require.Len(t, events, 1)
+	crumbs := events[0].Breadcrumbs
+	require.Len(t, crumbs, 2)
+	assert.Equal(t, "query", crumbs[0].Category)
+	assert.Equal(t, sentry.LevelInfo, crumbs[0].Level)
+	assert.Equal(t, "select * from users where name = ? and token = ?", crumbs[0].Message)
+	assert.Equal(t, []any{"alice", hypersql.RedactedSecret}, crumbs[0].Data["args"])
+	assert.Equal(t, sentry.LevelError, crumbs[1].Level)
+	assert.Equal(t, hypersql.ErrNameNoRows.String(), crumbs[1].Data["err_name"])
+}
+
+func TestHook_Spans(t *testing.T) {
+	hub, tr := newTestHub(t)
+	h, err := New(Hub(hub), Info(testInfo), Breadcrumbs(false))
+	require.NoError(t, err)
+
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	// No span is started without a parent.
+	runHook(h.(*hook), ctx, "select 0", nil)
+
+	tx := sentry.StartTransaction(ctx, "request")
+	runHook(h.(*hook), tx.Context(), "select 1", nil)
+	runHook(h.(*hook), tx.Context(), "select 2", errors.New("failed"))
+	runHook(h.(*hook), tx.Context(), "select 3", driver.ErrSkip)
+	tx.Finish()
+
+	var txEvent *sentry.Event
+	for _, e := range tr.Events() {
+		if e.Type == "transaction" {
+			txEvent = e
+		}
+	}
+	require.NotNil(t, txEvent)
+	require.Len(t, txEvent.Spans, 2)
+	s1, s2 := txEvent.Spans[0], txEvent.Spans[1]
+	assert.Equal(t, SpanOp, s1.Op)
+	assert.Equal(t, "select 1", s1.Description)
+	assert.Equal(t, sentry.SpanStatusOK, s1.Status)
+	assert.Equal(t, "postgres", s1.Tags[TagDialect])
+	assert.Equal(t, "app", s1.Data[TagDBName])
+	assert.Equal(t, "select 2", s2.Description)
+	assert.Equal(t, sentry.SpanStatusInternalError, s2.Status)
+	assert.Equal(t, hypersql.ErrNameUnsupported.String(), s2.Tags[TagErrName])
+}
+
+func TestHub_Nil(t *testing.T) {
+	h, err := New(Hub(nil))
+	require.NoError(t, err)
+	assert.Same(t, sentry.CurrentHub(), h.(*hook).hub)
+}
@@ -0,0 +1,108 @@
+package slowquery
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/blink-io/hypersql"
+)
+
+// explainer returns the plan of query on conn.
+type explainer func(ctx context.Context, conn *sql.Conn, query string, args []any) (string, error)
+
+var errUnsupportedExplain = errors.New("EXPLAIN is not supported by the dialect")
+
+// explainables are the first keywords of the statements which can be explained.
+var explainables = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "WITH", "REPLACE", "MERGE"}
+
+var explainers = map[string]explainer{
+	hypersql.DialectPostgres:  prefixExplainer("EXPLAIN (FORMAT JSON) "),
+	hypersql.DialectMySQL:     prefixExplainer("EXPLAIN FORMAT=JSON "),
+	hypersql.DialectSQLite:    prefixExplainer("EXPLAIN QUERY PLAN "),
+	hypersql.DialectSQLite3:   prefixExplainer("EXPLAIN QUERY PLAN "),
+	hypersql.DialectSQLServer: sqlServerExplain,
+}
+
+func explain(ctx context.Context, db *sql.DB, dialect string, query string, args []any) (string, error) {
+	e, ok := explainers[dialect]
+	if !ok {
+		return "", errUnsupportedExplain
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return e(ctx, conn, query, args)
+}
+
+// prefixExplainer explains by the statement prefixed by prefix, the plan is in its last column.
+func prefixExplainer(prefix string) explainer {
+	return func(ctx context.Context, conn *sql.Conn, query string, args []any) (string, error) {
+		rows, err := conn.QueryContext(ctx, prefix+query, args...)
+		if err != nil {
+			return "", err
+		}
+		return readPlan(rows)
+	}
+}
+
+// sqlServerExplain explains by SHOWPLAN_XML, which must be set in its own batch.
+// The connection is discarded if SHOWPLAN_XML can't be turned off, otherwise later statements on it would only be planned.
+func sqlServerExplain(ctx context.Context, conn *sql.Conn, query string, args []any) (plan string, err error) {
+	if _, err = conn.ExecContext(ctx, "SET SHOWPLAN_XML ON"); err != nil {
+		return "", err
+	}
+	defer func() {
+		if _, offErr := conn.ExecContext(context.WithoutCancel(ctx), "SET SHOWPLAN_XML OFF"); offErr != nil {
+			_ = conn.Raw(func(any) error {
+				return driver.ErrBadConn
+			})
+			err = errors.Join(err, offErr)
+		}
+	}()
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	return readPlan(rows)
+}
+
+// readPlan joins the last column of rows by lines.
+func readPlan(rows *sql.Rows) (string, error) {
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	values := make([]any, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	var lines []string
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		switch v := values[len(values)-1].(type) {
+		case []byte:
+			lines = append(lines, string(v))
+		default:
+			lines = append(lines, fmt.Sprint(v))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func explainable(query string) bool {
+	return slices.Contains(explainables, hypersql.QueryKeyword(query))
+}
@@ -0,0 +1,60 @@
+package slowquery
+
+import (
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+type Option func(*Hook)
+
+// Logger sets the logger, it is slog.Default() by default.
+func Logger(logger *slog.Logger) Option {
+	return func(h *Hook) {
+		h.logger = logger
+	}
+}
+
+// Level sets the level of slow queries, it is slog.LevelWarn by default.
+func Level(level slog.Level) Option {
+	return func(h *Hook) {
+		h.level = level
+	}
+}
+
+// Threshold sets the duration from which a query is slow, it is DefaultThreshold by default.
+func Threshold(d time.Duration) Option {
+	return func(h *Hook) {
+		h.threshold = d
+	}
+}
+
+// PatternThreshold sets the threshold of the queries matching re, which overrides Threshold.
+// The first matched pattern is used when there are more than one.
+func PatternThreshold(re *regexp.Regexp, d time.Duration) Option {
+	return func(h *Hook) {
+		h.patterns = append(h.patterns, patternThreshold{re: re, threshold: d})
+	}
+}
+
+// SampleRate logs the given fraction of the slow queries, it is 1 by default.
+func SampleRate(rate float64) Option {
+	return func(h *Hook) {
+		h.sampleRate = rate
+	}
+}
+
+// Explain captures the plans of slow queries by the EXPLAIN of the dialect on a separate connection.
+// The hook must be installed by Hook.Apply to explain, and the plans are limited to DML statements.
+func Explain(enabled bool) Option {
+	return func(h *Hook) {
+		h.explain = enabled
+	}
+}
+
+// ExplainTimeout bounds the EXPLAIN of a slow query, it is DefaultExplainTimeout by default.
+func ExplainTimeout(d time.Duration) Option {
+	return func(h *Hook) {
+		h.explainTimeout = d
+	}
+}
@@ -0,0 +1,172 @@
+package slowquery
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/blink-io/hypersql"
+	"github.com/qustavo/sqlhooks/v2"
+)
+
+const (
+	// DefaultThreshold is the duration from which a query is slow when Threshold is not set.
+	DefaultThreshold = time.Second
+
+	// DefaultExplainTimeout bounds the EXPLAIN of a slow query when ExplainTimeout is not set.
+	DefaultExplainTimeout = 5 * time.Second
+)
+
+const msgSlow = "SQL slow query"
+
+var _ interface {
+	sqlhooks.Hooks
+	sqlhooks.OnErrorer
+} = (*Hook)(nil)
+
+type (
+	startKey struct{}
+
+	// explainKey marks the context of EXPLAIN, which is not logged.
+	explainKey struct{}
+)
+
+type (
+	// Hook logs the slow queries by slog, optionally with their plans.
+	// The plans are captured asynchronously on a separate connection after the query finishes,
+	// the entry is logged when the EXPLAIN is done. One EXPLAIN runs at a time, and the slow
+	// queries during it are logged without plans.
+	Hook struct {
+		logger *slog.Logger
+		level  slog.Level
+
+		threshold time.Duration
+		patterns  []patternThreshold
+
+		sampleRate float64
+
+		explain        bool
+		explainTimeout time.Duration
+		explaining     atomic.Bool
+		dialect        string
+		db             atomic.Pointer[sql.DB]
+	}
+
+	patternThreshold struct {
+		re        *regexp.Regexp
+		threshold time.Duration
+	}
+)
+
+func New(ops ...Option) *Hook {
+	h := &Hook{
+		level:          slog.LevelWarn,
+		threshold:      DefaultThreshold,
+		sampleRate:     1,
+		explainTimeout: DefaultExplainTimeout,
+	}
+	for _, o := range ops {
+		o(h)
+	}
+	if h.logger == nil {
+		h.logger = slog.Default()
+	}
+	return h
+}
+
+// Apply adds h to the driver hooks of c, and keeps the created sql.DB and the dialect of c for EXPLAIN.
+// A Hook is applied to one Config.
+func (h *Hook) Apply(c *hypersql.Config) {
+	h.dialect = hypersql.GetFormalDialect(c.Dialect)
+	c.DriverHooks = append(c.DriverHooks, h)
+	c.AfterHandlers = append(c.AfterHandlers, func(ctx context.Context, db *sql.DB) error {
+		h.db.Store(db)
+		return nil
+	})
+}
+
+func (h *Hook) Before(ctx context.Context, query string, args ...any) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (h *Hook) After(ctx context.Context, query string, args ...any) (context.Context, error) {
+	attrs, ok := h.slow(ctx, query)
+	if !ok {
+		return ctx, nil
+	}
+	db := h.db.Load()
+	if !h.explain || db == nil || !explainable(query) || !h.explaining.CompareAndSwap(false, true) {
+		h.logger.LogAttrs(ctx, h.level, msgSlow, attrs...)
+		return ctx, nil
+	}
+
+	// The connection of the query is still held, so EXPLAIN runs after it is released.
+	lctx := context.WithoutCancel(ctx)
+	go func() {
+		defer h.explaining.Store(false)
+		ectx, cancel := context.WithTimeout(context.WithValue(lctx, explainKey{}, true), h.explainTimeout)
+		defer cancel()
+		if plan, err := explain(ectx, db, h.dialect, query, args); err != nil {
+			attrs = append(attrs, slog.String("explain_error", err.Error()))
+		} else {
+			attrs = append(attrs, slog.String("plan", plan))
+		}
+		h.logger.LogAttrs(lctx, h.level, msgSlow, attrs...)
+	}()
+	return ctx, nil
+}
+
+// OnError logs the slow queries which fail like After, with the error and its hypersql.ErrName but without plans.
+func (h *Hook) OnError(ctx context.Context, err error, query string, args ...any) error {
+	// database/sql retries the skipped statements in other ways.
+	if err == nil || errors.Is(err, driver.ErrSkip) {
+		return err
+	}
+	if attrs, ok := h.slow(ctx, query); ok {
+		attrs = append(attrs,
+			slog.Any("error", err),
+			slog.String("err_name", hypersql.WrapError(err).Name().String()),
+		)
+		h.logger.LogAttrs(ctx, h.level, msgSlow, attrs...)
+	}
+	return err
+}
+
+// slow returns the attrs of query if it is slow, sampled and logged.
+func (h *Hook) slow(ctx context.Context, query string) ([]slog.Attr, bool) {
+	start, ok := ctx.Value(startKey{}).(time.Time)
+	if !ok || ctx.Value(explainKey{}) != nil {
+		return nil, false
+	}
+	d := time.Since(start)
+	threshold := h.thresholdOf(query)
+	if threshold <= 0 || d < threshold || !h.sampled() || !h.logger.Enabled(ctx, h.level) {
+		return nil, false
+	}
+	return []slog.Attr{
+		slog.String("query", query),
+		slog.Duration("duration", d),
+		slog.Duration("threshold", threshold),
+	}, true
+}
+
+// thresholdOf returns the threshold of the first pattern matching query, or the global one.
+// A query is never slow if its threshold is not positive.
+func (h *Hook) thresholdOf(query string) time.Duration {
+	for _, p := range h.patterns {
+		if p.re.MatchString(query) {
+			return p.threshold
+		}
+	}
+	return h.threshold
+}
+
+func (h *Hook) sampled() bool {
+	return h.sampleRate >= 1 || (h.sampleRate > 0 && rand.Float64() < h.sampleRate)
+}
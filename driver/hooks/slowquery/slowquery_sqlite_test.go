@@ -0,0 +1,54 @@
+//go:build sqlite
+
+package slowquery
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/blink-io/hypersql"
+	"github.com/blink-io/hypersql/sqlite"
+	sqliteparams "github.com/blink-io/hypersql/sqlite/params"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestHook_Explain(t *testing.T) {
+	ctx := context.Background()
+	l := new(testLog)
+	c := &hypersql.Config{
+		Dialect: hypersql.DialectSQLite,
+		Name:    "file:slowquery.db",
+		Params: hypersql.ConfigParams{
+			sqliteparams.ConnParams.Cache: sqlite.CacheShared,
+			sqliteparams.ConnParams.Mode:  sqlite.ModeMemory,
+		},
+	}
+	New(Logger(l.logger()),
+		Threshold(time.Hour),
+		PatternThreshold(regexp.MustCompile(`from users`), time.Nanosecond),
+		Explain(true),
+	).Apply(c)
+
+	db, err := hypersql.NewSqlDB(c)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, "create table users (id integer primary key, name text)")
+	require.NoError(t, err)
+	var name string
+	err = db.QueryRowContext(ctx, "select name from users where id = ?", 1).Scan(&name)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.Eventually(t, func() bool {
+		return len(l.records(t)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	r := l.records(t)[0]
+	assert.Equal(t, "select name from users where id = ?", r["query"])
+	assert.Contains(t, r["plan"], "SEARCH users USING INTEGER PRIMARY KEY")
+	assert.NotContains(t, r, "explain_error")
+}
@@ -0,0 +1,114 @@
+package slowquery
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blink-io/hypersql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLog struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *testLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+func (l *testLog) logger() *slog.Logger {
+	return slog.New(slog.NewJSONHandler(l, nil))
+}
+
+func (l *testLog) records(t *testing.T) []map[string]any {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(l.buf.String()), "\n") {
+		if len(line) == 0 {
+			continue
+		}
+		var r map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &r))
+		records = append(records, r)
+	}
+	return records
+}
+
+func slowQuery(h *Hook, query string, d time.Duration) {
+	ctx, _ := h.Before(context.Background(), query)
+	time.Sleep(d)
+	_, _ = h.After(ctx, query)
+}
+
+func TestHook(t *testing.T) {
+	t.Run("threshold", func(t *testing.T) {
+		l := new(testLog)
+		h := New(Logger(l.logger()),
+			Threshold(time.Hour),
+			PatternThreshold(regexp.MustCompile(`(?i)^select \* from reports`), time.Millisecond),
+			PatternThreshold(regexp.MustCompile(`(?i)^select`), 0),
+		)
+		slowQuery(h, "select * from reports", 2*time.Millisecond)
+		slowQuery(h, "select * from users", 2*time.Millisecond)
+		slowQuery(h, "update users set name = ''", 2*time.Millisecond)
+
+		records := l.records(t)
+		require.Len(t, records, 1)
+		assert.Equal(t, "WARN", records[0]["level"])
+		assert.Equal(t, msgSlow, records[0]["msg"])
+		assert.Equal(t, "select * from reports", records[0]["query"])
+		assert.Equal(t, float64(time.Millisecond), records[0]["threshold"])
+		assert.GreaterOrEqual(t, records[0]["duration"], float64(2*time.Millisecond))
+		assert.NotContains(t, records[0], "plan")
+	})
+
+	t.Run("sample", func(t *testing.T) {
+		l := new(testLog)
+		h := New(Logger(l.logger()), Threshold(time.Nanosecond), SampleRate(0))
+		slowQuery(h, "select 1", time.Millisecond)
+		assert.Empty(t, l.records(t))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		l := new(testLog)
+		h := New(Logger(l.logger()), Threshold(time.Millisecond))
+		failedQuery := func(query string, d time.Duration, err error) {
+			ctx, _ := h.Before(context.Background(), query)
+			time.Sleep(d)
+			assert.Same(t, err, h.OnError(ctx, err, query))
+		}
+		uniqueErr := hypersql.ErrConstraintUnique.As("23505", "duplicate key", nil)
+		failedQuery("insert into users (name) values ($1)", 2*time.Millisecond, uniqueErr)
+		failedQuery("select 1", 2*time.Millisecond, driver.ErrSkip)
+		failedQuery("select 2", 0, uniqueErr)
+
+		records := l.records(t)
+		require.Len(t, records, 1)
+		assert.Equal(t, msgSlow, records[0]["msg"])
+		assert.Equal(t, "insert into users (name) values ($1)", records[0]["query"])
+		assert.Equal(t, uniqueErr.Error(), records[0]["error"])
+		assert.Equal(t, hypersql.ErrNameConstraintUnique.String(), records[0]["err_name"])
+	})
+}
+
+func TestExplainable(t *testing.T) {
+	assert.True(t, explainable(" select 1"))
+	assert.True(t, explainable("(SELECT 1)"))
+	assert.True(t, explainable("WITH t AS (SELECT 1) DELETE FROM users"))
+	assert.False(t, explainable("EXPLAIN SELECT 1"))
+	assert.False(t, explainable("create table t (id int)"))
+	assert.False(t, explainable(""))
+}
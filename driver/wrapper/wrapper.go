@@ -0,0 +1,325 @@
+// Package wrapper provides the bases of the driver wrappers, which forward the calls to the wrapped driver
+// and implement the optional interfaces that database/sql checks.
+// A wrapper embeds them and only overrides the methods it intercepts, and wraps the driver by WrapDriver.
+package wrapper
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+
+	"github.com/qustavo/sqlhooks/v2"
+)
+
+type (
+	// Conn forwards the calls to the wrapped driver.Conn.
+	// Prepare and Begin call the methods of Conn, so a wrapper which overrides PrepareContext or BeginTx overrides them too.
+	Conn struct {
+		driver.Conn
+	}
+
+	// Stmt forwards the calls to the wrapped driver.Stmt, Conn is used to check the named values
+	// when the statement does not.
+	Stmt struct {
+		driver.Stmt
+		Conn driver.Conn
+	}
+
+	// Rows forwards the calls to the wrapped driver.Rows.
+	// The column type methods return what database/sql assumes when the driver rows do not implement them.
+	Rows struct {
+		driver.Rows
+	}
+
+	// Unwrapper is implemented by the wrapped connections.
+	Unwrapper interface {
+		Unwrap() driver.Conn
+	}
+
+	// OpenFunc returns the wrapped connection of open, which opens a connection of the wrapped driver or connector.
+	OpenFunc func(open func() (driver.Conn, error)) (driver.Conn, error)
+
+	wrappedDriver struct {
+		driver.Driver
+		fn OpenFunc
+	}
+
+	// contextDriver is wrappedDriver of a driver.DriverContext.
+	contextDriver struct {
+		*wrappedDriver
+	}
+
+	wrappedConnector struct {
+		c   driver.Connector
+		drv *contextDriver
+	}
+
+	// openDriver opens the connection by the func.
+	openDriver func() (driver.Conn, error)
+)
+
+var (
+	_ driver.ConnPrepareContext = (*Conn)(nil)
+	_ driver.ConnBeginTx        = (*Conn)(nil)
+	_ driver.ExecerContext      = (*Conn)(nil)
+	_ driver.QueryerContext     = (*Conn)(nil)
+	_ driver.Pinger             = (*Conn)(nil)
+	_ driver.SessionResetter    = (*Conn)(nil)
+	_ driver.Validator          = (*Conn)(nil)
+	_ driver.NamedValueChecker  = (*Conn)(nil)
+	_ Unwrapper                 = (*Conn)(nil)
+	_ driver.StmtExecContext    = (*Stmt)(nil)
+	_ driver.StmtQueryContext   = (*Stmt)(nil)
+	_ driver.NamedValueChecker  = (*Stmt)(nil)
+
+	_ driver.RowsNextResultSet              = (*Rows)(nil)
+	_ driver.RowsColumnTypeScanType         = (*Rows)(nil)
+	_ driver.RowsColumnTypeDatabaseTypeName = (*Rows)(nil)
+	_ driver.RowsColumnTypeLength           = (*Rows)(nil)
+	_ driver.RowsColumnTypeNullable         = (*Rows)(nil)
+	_ driver.RowsColumnTypePrecisionScale   = (*Rows)(nil)
+
+	_ driver.DriverContext = (*contextDriver)(nil)
+	_ driver.Connector     = (*wrappedConnector)(nil)
+)
+
+// WrapDriver returns a driver which opens the connections of drv by fn. It implements driver.DriverContext
+// if drv does, so the context of Connect is passed to the connector of drv.
+func WrapDriver(drv driver.Driver, fn OpenFunc) driver.Driver {
+	d := &wrappedDriver{Driver: drv, fn: fn}
+	if _, ok := drv.(driver.DriverContext); ok {
+		return &contextDriver{d}
+	}
+	return d
+}
+
+// WrapHooks is sqlhooks.Wrap which keeps driver.DriverContext of drv, see WrapDriver.
+func WrapHooks(drv driver.Driver, hooks sqlhooks.Hooks) driver.Driver {
+	return WrapDriver(drv, func(open func() (driver.Conn, error)) (driver.Conn, error) {
+		return sqlhooks.Wrap(openDriver(open), hooks).Open("")
+	})
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	return d.fn(func() (driver.Conn, error) {
+		return d.Driver.Open(name)
+	})
+}
+
+func (d *contextDriver) OpenConnector(name string) (driver.Connector, error) {
+	c, err := d.Driver.(driver.DriverContext).OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConnector{c: c, drv: d}, nil
+}
+
+func (c *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.drv.fn(func() (driver.Conn, error) {
+		return c.c.Connect(ctx)
+	})
+}
+
+func (c *wrappedConnector) Driver() driver.Driver {
+	return c.drv
+}
+
+func (f openDriver) Open(string) (driver.Conn, error) {
+	return f()
+}
+
+// Unwrap returns the wrapped connection, see UnwrapConn.
+func (c *Conn) Unwrap() driver.Conn {
+	return c.Conn
+}
+
+func (c *Conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return pc.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *Conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *Conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 || opts.ReadOnly {
+		return nil, errors.New("driver does not support non-default transaction options")
+	}
+	return c.Conn.Begin()
+}
+
+func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if ec, ok := c.Conn.(driver.ExecerContext); ok {
+		return ec.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if qc, ok := c.Conn.(driver.QueryerContext); ok {
+		return qc.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *Conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *Conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *Conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *Conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return ec.ExecContext(ctx, args)
+	}
+	values, err := NamedValuesToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(values)
+}
+
+func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return qc.QueryContext(ctx, args)
+	}
+	values, err := NamedValuesToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(values)
+}
+
+func (s *Stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	if nc, ok := s.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (r *Rows) HasNextResultSet() bool {
+	if nr, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return nr.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *Rows) NextResultSet() error {
+	if nr, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return nr.NextResultSet()
+	}
+	return io.EOF
+}
+
+func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return ct.ColumnTypeScanType(index)
+	}
+	return reflect.TypeFor[any]()
+}
+
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return ct.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *Rows) ColumnTypeLength(index int) (int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return ct.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *Rows) ColumnTypeNullable(index int) (bool, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return ct.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *Rows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return ct.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+// UnwrapConn returns the native connection of the driver under conn, e.g. *stdlib.Conn of pgx,
+// by removing the wrappers of this package, sqlhooks and otelsql.
+// It is used with the connection passed to sql.Conn.Raw.
+func UnwrapConn(conn any) any {
+	for {
+		switch c := conn.(type) {
+		case Unwrapper:
+			conn = c.Unwrap()
+		// The connection of otelsql.
+		case interface{ Raw() driver.Conn }:
+			conn = c.Raw()
+		case *sqlhooks.Conn:
+			conn = c.Conn
+		case *sqlhooks.ExecerContext:
+			conn = c.Conn.Conn
+		case *sqlhooks.QueryerContext:
+			conn = c.Conn.Conn
+		case *sqlhooks.ExecerQueryerContext:
+			conn = c.Conn.Conn
+		case *sqlhooks.ExecerQueryerContextWithSessionResetter:
+			conn = c.Conn.Conn
+		case *sqlhooks.SessionResetter:
+			conn = c.Conn.Conn
+		default:
+			return conn
+		}
+	}
+}
+
+// NamedValuesToValues converts args for the driver statements which do not support the context methods.
+func NamedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		if len(a.Name) > 0 {
+			return nil, errors.New("driver does not support the use of Named Parameters")
+		}
+		values[i] = a.Value
+	}
+	return values, nil
+}
@@ -23,6 +23,10 @@ const (
 	ErrNameConstraintNotNull ErrName = "not_null_constraint"
 
 	ErrNameConstraintForeignKey ErrName = "foreign_key_constraint"
+
+	ErrNameReadOnly ErrName = "read_only"
+
+	ErrNameShuttingDown ErrName = "shutting_down"
 )
 
 var (
@@ -49,7 +53,7 @@ var (
 
 	ErrUnsupported = ErrNameUnsupported.ToError()
 
-	ErrNoRows = ErrNameUnsupported.ToError()
+	ErrNoRows = ErrNameNoRows.ToError()
 
 	ErrTooManyRows = ErrNameTooManyRows.ToError()
 
@@ -60,6 +64,10 @@ var (
 	ErrConstraintNotNull = ErrNameConstraintNotNull.ToError()
 
 	ErrConstraintForeignKey = ErrNameConstraintForeignKey.ToError()
+
+	ErrReadOnly = ErrNameReadOnly.ToError()
+
+	ErrShuttingDown = ErrNameShuttingDown.NewError("", "database is shutting down", nil)
 )
 
 type Error struct {
@@ -125,6 +133,8 @@ func WrapError(e error) *Error {
 		newErr = handleSQLiteError(tErr)
 	} else if tErr, ok := isTargetErr[*SQLServerError](e); ok {
 		newErr = handleSQLServerError(tErr)
+	} else if tErr, ok := isTargetErr[SQLServerError](e); ok {
+		newErr = handleSQLServerError(&tErr)
 	} else if ef, ok := handleCommonError(e); ok {
 		newErr = ef(e)
 	} else {
@@ -166,6 +176,16 @@ func IsErrConstraintForeignKey(e error) bool {
 	return ErrNameEquals(e, ErrNameConstraintForeignKey)
 }
 
+// IsErrReadOnly reports whether the statement is rejected because the server or the transaction is read-only,
+// e.g. the connection is still connected to the old primary after a failover.
+func IsErrReadOnly(e error) bool {
+	return ErrNameEquals(e, ErrNameReadOnly)
+}
+
+func IsErrShuttingDown(e error) bool {
+	return ErrNameEquals(e, ErrNameShuttingDown)
+}
+
 func ErrNameEquals(e error, name ErrName) bool {
 	if se, ok := isTargetErr[*Error](e); ok {
 		return se.name == name
@@ -1,7 +1,15 @@
 package hypersql
 
+import "database/sql"
+
 var commonErrorHandlers = make(map[error]func(error) *Error)
 
+func init() {
+	RegisterCommonErrorHandler(sql.ErrNoRows, func(e error) *Error {
+		return ErrNoRows.As("", e.Error(), e)
+	})
+}
+
 func RegisterCommonErrorHandler(e error, f func(error) *Error) {
 	commonErrorHandlers[e] = f
 }
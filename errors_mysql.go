@@ -1,6 +1,8 @@
 package hypersql
 
 import (
+	"strings"
+
 	"github.com/go-sql-driver/mysql"
 	"github.com/spf13/cast"
 )
@@ -22,6 +24,16 @@ var mysqlErrorHandlers = map[uint16]func(*mysql.MySQLError) *Error{
 		return ErrTooManyRows.As(code, e.Message, e)
 	},
 
+	// Error number: 1290; Symbol: ER_OPTION_PREVENTS_STATEMENT; SQLSTATE: HY000
+	// Message: The MySQL server is running with the %s option so it cannot execute this statement
+	// Only --read-only and --super-read-only make it a read-only error, e.g. not --secure-file-priv.
+	1290: func(e *mysql.MySQLError) *Error {
+		if strings.Contains(strings.ToLower(e.Message), "read-only") {
+			return mysqlReadOnlyErrHandler(e)
+		}
+		return ErrOther.As(cast.ToString(e.Number), e.Message, e)
+	},
+
 	// Error number: 1329; Symbol: ER_SP_FETCH_NO_DATA; SQLSTATE: 02000
 	// Message: No data - zero rows fetched, selected, or processed
 	1329: func(e *mysql.MySQLError) *Error {
@@ -51,6 +63,10 @@ var mysqlErrorHandlers = map[uint16]func(*mysql.MySQLError) *Error{
 	// Message: Cannot add or update a child row: a foreign key constraint fails (%s)
 	1452: mysqlFKConstraintErrHandler,
 
+	// Error number: 1836; Symbol: ER_READ_ONLY_MODE; SQLSTATE: HY000
+	// Message: Running in read-only mode
+	1836: mysqlReadOnlyErrHandler,
+
 	// Error number: 3819; Symbol: ER_CHECK_CONSTRAINT_VIOLATED; SQLSTATE: HY000
 	// Message: Check constraint '%s' is violated.
 	// ER_CHECK_CONSTRAINT_VIOLATED was added in 8.0.16.
@@ -71,6 +87,11 @@ func mysqlFKConstraintErrHandler(e *mysql.MySQLError) *Error {
 	return ErrConstraintForeignKey.As(code, e.Message, e)
 }
 
+func mysqlReadOnlyErrHandler(e *mysql.MySQLError) *Error {
+	code := cast.ToString(e.Number)
+	return ErrReadOnly.As(code, e.Message, e)
+}
+
 func mysqlCheckConstraintErrHandler(e *mysql.MySQLError) *Error {
 	code := cast.ToString(e.Number)
 	return ErrConstraintForeignKey.As(code, e.Message, e)
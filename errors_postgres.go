@@ -39,6 +39,10 @@ var pgxErrorHandlers = map[string]func(*pgconn.PgError) *Error{
 	"23514": func(e *pgconn.PgError) *Error {
 		return ErrConstraintCheck.As(e.Code, e.Message, e)
 	},
+	// 25006	read_only_sql_transaction
+	"25006": func(e *pgconn.PgError) *Error {
+		return ErrReadOnly.As(e.Code, e.Message, e)
+	},
 }
 
 func RegisterPgxErrorHandler(code string, fn func(*pgconn.PgError) *Error) {
@@ -11,6 +11,10 @@ var sqlServerErrorHandlers = map[int32]func(*mssql.Error) *Error{
 	2627: func(e *mssql.Error) *Error {
 		return ErrOther.As(cast.ToString(e.Number), e.Message, e)
 	},
+	// 3906: Failed to update database because the database is read-only.
+	3906: func(e *mssql.Error) *Error {
+		return ErrReadOnly.As(cast.ToString(e.Number), e.Message, e)
+	},
 }
 
 func handleSQLServerError(e *mssql.Error) *Error {
@@ -1,6 +1,7 @@
 package hypersql
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"testing"
@@ -39,6 +40,14 @@ func TestSQLiteErr(t *testing.T) {
 	require.NotNil(t, newErr)
 }
 
+func TestNoRowsErr(t *testing.T) {
+	newErr := WrapError(sql.ErrNoRows)
+
+	require.Equal(t, ErrNameNoRows, newErr.Name())
+	require.ErrorIs(t, newErr, sql.ErrNoRows)
+	require.True(t, IsErrNoRows(ErrNoRows))
+}
+
 func TestErrEqual(t *testing.T) {
 	var cause1 = errors.New("cause1")
 	var cause2 = errors.New("cause2 from blink-x")
@@ -0,0 +1,178 @@
+package hypersql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/blink-io/hypersql/driver/wrapper"
+)
+
+type (
+	FailoverOption func(*failoverOptions)
+
+	// FailoverHandler is called with the error that a failover is detected from.
+	FailoverHandler func(err error)
+
+	failoverOptions struct {
+		drain   bool
+		detect  func(error) bool
+		handler FailoverHandler
+	}
+
+	failoverDriver struct {
+		ops *failoverOptions
+		// gen is increased when the pool is drained, connections of older generations are discarded.
+		gen atomic.Uint64
+	}
+
+	failoverConn struct {
+		wrapper.Conn
+		drv *failoverDriver
+		gen uint64
+		bad atomic.Bool
+	}
+
+	failoverStmt struct {
+		wrapper.Stmt
+		c *failoverConn
+	}
+
+	failoverTx struct {
+		driver.Tx
+		c *failoverConn
+	}
+)
+
+// DrainPoolOnFailover discards all the connections opened before a failover is detected,
+// not only the one which got the error.
+func DrainPoolOnFailover() FailoverOption {
+	return func(o *failoverOptions) {
+		o.drain = true
+	}
+}
+
+// WithFailoverHandler sets the handler which is called when a failover is detected.
+func WithFailoverHandler(h FailoverHandler) FailoverOption {
+	return func(o *failoverOptions) {
+		o.handler = h
+	}
+}
+
+// WithFailoverDetect sets the function that reports whether an error means a failover, IsErrReadOnly is used by default.
+func WithFailoverDetect(fn func(error) bool) FailoverOption {
+	return func(o *failoverOptions) {
+		o.detect = fn
+	}
+}
+
+// FailoverDetector returns a DriverWrapper which detects a writer failover, e.g. Aurora or Patroni,
+// by the read-only errors of the old primary (Postgres 25006, MySQL 1290/1836, SQLServer 3906).
+// The connection which gets such an error is marked bad, and the error is returned with driver.ErrBadConn,
+// so database/sql discards it and retries outside transactions on a new connection, which reaches the new writer.
+func FailoverDetector(ops ...FailoverOption) DriverWrapper {
+	o := &failoverOptions{detect: IsErrReadOnly}
+	for _, op := range ops {
+		op(o)
+	}
+	return func(drv driver.Driver) driver.Driver {
+		d := &failoverDriver{ops: o}
+		return wrapper.WrapDriver(drv, d.open)
+	}
+}
+
+func (d *failoverDriver) open(open func() (driver.Conn, error)) (driver.Conn, error) {
+	conn, err := open()
+	if err != nil {
+		return nil, err
+	}
+	return &failoverConn{Conn: wrapper.Conn{Conn: conn}, drv: d, gen: d.gen.Load()}, nil
+}
+
+// detected marks the connection bad and drains the pool if it is required.
+func (c *failoverConn) detected(err error) error {
+	if err == nil || errors.Is(err, driver.ErrBadConn) || !c.drv.ops.detect(err) {
+		return err
+	}
+	c.bad.Store(true)
+	if c.drv.ops.drain {
+		// Only the first connection of the generation drains the pool.
+		c.drv.gen.CompareAndSwap(c.gen, c.gen+1)
+	}
+	if h := c.drv.ops.handler; h != nil {
+		h(err)
+	}
+	return fmt.Errorf("%w: %w", driver.ErrBadConn, err)
+}
+
+func (c *failoverConn) valid() bool {
+	return !c.bad.Load() && c.gen == c.drv.gen.Load()
+}
+
+func (c *failoverConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *failoverConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, c.detected(err)
+	}
+	return &failoverStmt{Stmt: wrapper.Stmt{Stmt: stmt, Conn: c}, c: c}, nil
+}
+
+func (c *failoverConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *failoverConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.Conn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, c.detected(err)
+	}
+	return &failoverTx{Tx: tx, c: c}, nil
+}
+
+func (c *failoverConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.Conn.ExecContext(ctx, query, args)
+	return res, c.detected(err)
+}
+
+func (c *failoverConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.Conn.QueryContext(ctx, query, args)
+	return rows, c.detected(err)
+}
+
+func (c *failoverConn) Ping(ctx context.Context) error {
+	if !c.valid() {
+		return driver.ErrBadConn
+	}
+	return c.detected(c.Conn.Ping(ctx))
+}
+
+func (c *failoverConn) ResetSession(ctx context.Context) error {
+	if !c.valid() {
+		return driver.ErrBadConn
+	}
+	return c.Conn.ResetSession(ctx)
+}
+
+func (c *failoverConn) IsValid() bool {
+	return c.valid() && c.Conn.IsValid()
+}
+
+func (s *failoverStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	res, err := s.Stmt.ExecContext(ctx, args)
+	return res, s.c.detected(err)
+}
+
+func (s *failoverStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := s.Stmt.QueryContext(ctx, args)
+	return rows, s.c.detected(err)
+}
+
+func (t *failoverTx) Commit() error {
+	return t.c.detected(t.Tx.Commit())
+}
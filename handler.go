@@ -9,4 +9,8 @@ type (
 	AfterHandler func(context.Context, *sql.DB) error
 
 	AfterHandlers []AfterHandler
+
+	CloseHandler func(context.Context, *sql.DB) error
+
+	CloseHandlers []CloseHandler
 )
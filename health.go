@@ -0,0 +1,266 @@
+package hypersql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	HealthStatusHealthy HealthStatus = "healthy"
+
+	HealthStatusDegraded HealthStatus = "degraded"
+
+	HealthStatusUnhealthy HealthStatus = "unhealthy"
+)
+
+// DefaultHealthThresholds is used by HealthReport when Config.HealthThresholds is not set.
+// A saturated pool is only degraded, so the instance is not taken out of rotation at peak load.
+var DefaultHealthThresholds = HealthThresholds{
+	PingLatencyDegraded:        100 * time.Millisecond,
+	PingLatencyUnhealthy:       time.Second,
+	ValidationLatencyDegraded:  200 * time.Millisecond,
+	ValidationLatencyUnhealthy: 2 * time.Second,
+	InUseRatioDegraded:         0.8,
+	WaitDurationDegraded:       100 * time.Millisecond,
+	WaitDurationUnhealthy:      time.Second,
+}
+
+type (
+	HealthStatus string
+
+	HealthReporter interface {
+		HealthReport(context.Context) *HealthReport
+	}
+
+	// HealthThresholds maps the measured values of HealthReport to a HealthStatus.
+	// A zero threshold is not checked.
+	HealthThresholds struct {
+		PingLatencyDegraded  time.Duration `json:"ping_latency_degraded" yaml:"ping_latency_degraded" toml:"ping_latency_degraded"`
+		PingLatencyUnhealthy time.Duration `json:"ping_latency_unhealthy" yaml:"ping_latency_unhealthy" toml:"ping_latency_unhealthy"`
+
+		ValidationLatencyDegraded  time.Duration `json:"validation_latency_degraded" yaml:"validation_latency_degraded" toml:"validation_latency_degraded"`
+		ValidationLatencyUnhealthy time.Duration `json:"validation_latency_unhealthy" yaml:"validation_latency_unhealthy" toml:"validation_latency_unhealthy"`
+
+		// InUseRatio is InUse / MaxOpenConnections, it is not checked for unlimited pools.
+		InUseRatioDegraded  float64 `json:"in_use_ratio_degraded" yaml:"in_use_ratio_degraded" toml:"in_use_ratio_degraded"`
+		InUseRatioUnhealthy float64 `json:"in_use_ratio_unhealthy" yaml:"in_use_ratio_unhealthy" toml:"in_use_ratio_unhealthy"`
+
+		// WaitDuration is the time spent waiting for connections since the previous report.
+		WaitDurationDegraded  time.Duration `json:"wait_duration_degraded" yaml:"wait_duration_degraded" toml:"wait_duration_degraded"`
+		WaitDurationUnhealthy time.Duration `json:"wait_duration_unhealthy" yaml:"wait_duration_unhealthy" toml:"wait_duration_unhealthy"`
+
+		// ExpectWritable reports unhealthy when the server is read-only or a replica.
+		ExpectWritable bool `json:"expect_writable" yaml:"expect_writable" toml:"expect_writable"`
+	}
+
+	HealthReport struct {
+		Name          string       `json:"name"`
+		Dialect       string       `json:"dialect"`
+		ServerVersion string       `json:"server_version,omitempty"`
+		Status        HealthStatus `json:"status"`
+		CheckedAt     time.Time    `json:"checked_at"`
+
+		Ping       LatencyHealth  `json:"ping"`
+		Validation LatencyHealth  `json:"validation"`
+		Pool       PoolHealth     `json:"pool"`
+		ReadOnly   ReadOnlyHealth `json:"read_only"`
+	}
+
+	LatencyHealth struct {
+		Status  HealthStatus  `json:"status"`
+		Skipped bool          `json:"skipped,omitempty"`
+		Latency time.Duration `json:"latency"`
+		Error   string        `json:"error,omitempty"`
+	}
+
+	PoolHealth struct {
+		Status HealthStatus `json:"status"`
+		PoolStats
+		InUseRatio float64 `json:"in_use_ratio"`
+		// WaitCountDelta and WaitDurationDelta are measured since the previous report.
+		WaitCountDelta    int64         `json:"wait_count_delta"`
+		WaitDurationDelta time.Duration `json:"wait_duration_delta"`
+	}
+
+	// PoolStats is the JSON serializable snapshot of sql.DBStats.
+	PoolStats struct {
+		MaxOpenConnections int           `json:"max_open_connections"`
+		OpenConnections    int           `json:"open_connections"`
+		InUse              int           `json:"in_use"`
+		Idle               int           `json:"idle"`
+		WaitCount          int64         `json:"wait_count"`
+		WaitDuration       time.Duration `json:"wait_duration"`
+		MaxIdleClosed      int64         `json:"max_idle_closed"`
+		MaxIdleTimeClosed  int64         `json:"max_idle_time_closed"`
+		MaxLifetimeClosed  int64         `json:"max_lifetime_closed"`
+	}
+
+	ReadOnlyHealth struct {
+		Status   HealthStatus `json:"status"`
+		Skipped  bool         `json:"skipped,omitempty"`
+		ReadOnly bool         `json:"read_only"`
+		Error    string       `json:"error,omitempty"`
+	}
+)
+
+var _ HealthReporter = (*DB)(nil)
+
+func NewPoolStats(s sql.DBStats) PoolStats {
+	return PoolStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration,
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
+
+// Healthy reports whether the status is not unhealthy, a degraded DB is still able to serve.
+func (s HealthStatus) Healthy() bool {
+	return s != HealthStatusUnhealthy
+}
+
+func (s HealthStatus) String() string {
+	return string(s)
+}
+
+// Worse returns the worse one of the two statuses.
+func (s HealthStatus) Worse(o HealthStatus) HealthStatus {
+	if healthStatusRank(o) > healthStatusRank(s) {
+		return o
+	}
+	return s
+}
+
+func healthStatusRank(s HealthStatus) int {
+	switch s {
+	case HealthStatusHealthy:
+		return 0
+	case HealthStatusDegraded:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// HealthReport measures the DB and maps the results to statuses by Config.HealthThresholds.
+// The report is bounded by Config.HealthCheckTimeout.
+func (d *DB) HealthReport(ctx context.Context) *HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, d.healthCheckTimeout())
+	defer cancel()
+
+	th := d.healthThresholds()
+	r := &HealthReport{
+		Name:          d.info.Name,
+		Dialect:       d.info.Dialect,
+		ServerVersion: d.info.Version,
+		CheckedAt:     time.Now(),
+	}
+
+	start := time.Now()
+	err := DoPingContext(ctx, d.DB)
+	r.Ping = newLatencyHealth(time.Since(start), err, th.PingLatencyDegraded, th.PingLatencyUnhealthy)
+
+	if q := d.Config().ValidationSQL; len(q) > 0 && err == nil {
+		start = time.Now()
+		_, verr := d.DB.ExecContext(ctx, q)
+		r.Validation = newLatencyHealth(time.Since(start), verr, th.ValidationLatencyDegraded, th.ValidationLatencyUnhealthy)
+	} else if len(q) > 0 {
+		r.Validation = LatencyHealth{Status: HealthStatusUnhealthy, Error: "skipped because ping failed"}
+	} else {
+		r.Validation = LatencyHealth{Status: HealthStatusHealthy, Skipped: true}
+	}
+
+	r.Pool = d.poolHealth(th)
+	r.ReadOnly = d.readOnlyHealth(ctx, th, err == nil)
+
+	r.Status = r.Ping.Status.
+		Worse(r.Validation.Status).
+		Worse(r.Pool.Status).
+		Worse(r.ReadOnly.Status)
+	return r
+}
+
+func (d *DB) healthThresholds() HealthThresholds {
+	if th := d.Config().HealthThresholds; th != nil {
+		return *th
+	}
+	return DefaultHealthThresholds
+}
+
+func (d *DB) poolHealth(th HealthThresholds) PoolHealth {
+	stats := d.DB.Stats()
+
+	d.mu.Lock()
+	prev := d.lastStats
+	d.lastStats = stats
+	d.mu.Unlock()
+
+	h := PoolHealth{
+		Status:            HealthStatusHealthy,
+		PoolStats:         NewPoolStats(stats),
+		WaitCountDelta:    stats.WaitCount - prev.WaitCount,
+		WaitDurationDelta: stats.WaitDuration - prev.WaitDuration,
+	}
+	if stats.MaxOpenConnections > 0 {
+		h.InUseRatio = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+		if th.InUseRatioUnhealthy > 0 && h.InUseRatio >= th.InUseRatioUnhealthy {
+			h.Status = HealthStatusUnhealthy
+		} else if th.InUseRatioDegraded > 0 && h.InUseRatio >= th.InUseRatioDegraded {
+			h.Status = HealthStatusDegraded
+		}
+	}
+	h.Status = h.Status.Worse(thresholdStatus(h.WaitDurationDelta, th.WaitDurationDegraded, th.WaitDurationUnhealthy))
+	return h
+}
+
+func (d *DB) readOnlyHealth(ctx context.Context, th HealthThresholds, reachable bool) ReadOnlyHealth {
+	queries, ok := readOnlyQueries[d.info.Dialect]
+	if !ok {
+		return ReadOnlyHealth{Status: HealthStatusHealthy, Skipped: true}
+	}
+	if !reachable {
+		return ReadOnlyHealth{Status: HealthStatusUnhealthy, Error: "skipped because ping failed"}
+	}
+
+	h := ReadOnlyHealth{Status: HealthStatusHealthy}
+	var err error
+	for _, q := range queries {
+		if err = d.DB.QueryRowContext(ctx, q).Scan(&h.ReadOnly); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		h.Status = HealthStatusUnhealthy
+		h.Error = err.Error()
+	} else if h.ReadOnly && th.ExpectWritable {
+		h.Status = HealthStatusUnhealthy
+	}
+	return h
+}
+
+func newLatencyHealth(latency time.Duration, err error, degraded, unhealthy time.Duration) LatencyHealth {
+	h := LatencyHealth{Latency: latency}
+	if err != nil {
+		h.Status = HealthStatusUnhealthy
+		h.Error = err.Error()
+	} else {
+		h.Status = thresholdStatus(latency, degraded, unhealthy)
+	}
+	return h
+}
+
+func thresholdStatus(v, degraded, unhealthy time.Duration) HealthStatus {
+	if unhealthy > 0 && v >= unhealthy {
+		return HealthStatusUnhealthy
+	}
+	if degraded > 0 && v >= degraded {
+		return HealthStatusDegraded
+	}
+	return HealthStatusHealthy
+}
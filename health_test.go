@@ -0,0 +1,41 @@
+package hypersql
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthStatus_Worse(t *testing.T) {
+	assert.Equal(t, HealthStatusDegraded, HealthStatusHealthy.Worse(HealthStatusDegraded))
+	assert.Equal(t, HealthStatusUnhealthy, HealthStatusUnhealthy.Worse(HealthStatusDegraded))
+	assert.True(t, HealthStatusDegraded.Healthy())
+	assert.False(t, HealthStatusUnhealthy.Healthy())
+}
+
+func TestThresholdStatus(t *testing.T) {
+	degraded, unhealthy := 10*time.Millisecond, 100*time.Millisecond
+	assert.Equal(t, HealthStatusHealthy, thresholdStatus(time.Millisecond, degraded, unhealthy))
+	assert.Equal(t, HealthStatusDegraded, thresholdStatus(50*time.Millisecond, degraded, unhealthy))
+	assert.Equal(t, HealthStatusUnhealthy, thresholdStatus(time.Second, degraded, unhealthy))
+	assert.Equal(t, HealthStatusHealthy, thresholdStatus(time.Hour, 0, 0))
+}
+
+func TestHealthReport_JSON(t *testing.T) {
+	r := &HealthReport{
+		Name:   "test",
+		Status: HealthStatusDegraded,
+		Ping:   LatencyHealth{Status: HealthStatusDegraded, Latency: time.Second},
+		Pool:   PoolHealth{Status: HealthStatusHealthy, PoolStats: PoolStats{InUse: 2, Idle: 1}},
+	}
+	data, err := json.Marshal(r)
+	require.NoError(t, err)
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, "degraded", m["status"])
+	assert.EqualValues(t, 2, m["pool"].(map[string]any)["in_use"])
+}
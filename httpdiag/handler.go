@@ -0,0 +1,203 @@
+package httpdiag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/blink-io/hypersql"
+)
+
+const (
+	PathLiveness  = "/livez"
+	PathReadiness = "/readyz"
+	PathStats     = "/stats"
+	PathConfig    = "/config"
+)
+
+const defaultTimeout = 5 * time.Second
+
+type (
+	// Database is served by Handler, *hypersql.DB implements it.
+	Database interface {
+		hypersql.HealthChecker
+		hypersql.HealthReporter
+	}
+
+	withStats interface {
+		Stats() sql.DBStats
+	}
+
+	withConfig interface {
+		Config() *hypersql.Config
+	}
+
+	LivenessResponse struct {
+		Status    hypersql.HealthStatus     `json:"status"`
+		Databases map[string]LivenessResult `json:"databases"`
+	}
+
+	LivenessResult struct {
+		Status hypersql.HealthStatus `json:"status"`
+		Error  string                `json:"error,omitempty"`
+	}
+
+	ReadinessResponse struct {
+		Status    hypersql.HealthStatus             `json:"status"`
+		Databases map[string]*hypersql.HealthReport `json:"databases"`
+	}
+)
+
+// Handler serves liveness, readiness and diagnostics endpoints for the registered databases.
+//
+//	GET /livez          200 if every database passes its health check, otherwise 503
+//	GET /readyz         200 if no database is unhealthy by its health report, otherwise 503
+//	GET /readyz/{name}  the same as /readyz for one database
+//	GET /stats          pool stats of every database, if ExposeStats is given
+//	GET /config         redacted config of every database, if ExposeConfig is given
+type Handler struct {
+	mu  sync.RWMutex
+	dbs map[string]Database
+
+	timeout      time.Duration
+	exposeStats  bool
+	exposeConfig bool
+
+	mux *http.ServeMux
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+func New(ops ...Option) *Handler {
+	h := &Handler{
+		dbs:     make(map[string]Database),
+		timeout: defaultTimeout,
+	}
+	for _, o := range ops {
+		o(h)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+PathLiveness, h.serveLiveness)
+	mux.HandleFunc("GET "+PathReadiness, h.serveReadiness)
+	mux.HandleFunc("GET "+PathReadiness+"/{name}", h.serveReadiness)
+	if h.exposeStats {
+		mux.HandleFunc("GET "+PathStats, h.serveStats)
+	}
+	if h.exposeConfig {
+		mux.HandleFunc("GET "+PathConfig, h.serveConfig)
+	}
+	h.mux = mux
+	return h
+}
+
+// Register adds the database with the given name, the previous one with the same name is replaced.
+func (h *Handler) Register(name string, db Database) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dbs[name] = db
+}
+
+// Deregister removes the database with the given name.
+func (h *Handler) Deregister(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.dbs, name)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) serveLiveness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	resp := LivenessResponse{
+		Status:    hypersql.HealthStatusHealthy,
+		Databases: make(map[string]LivenessResult),
+	}
+	for name, db := range h.snapshot() {
+		res := LivenessResult{Status: hypersql.HealthStatusHealthy}
+		if err := db.HealthCheck(ctx); err != nil {
+			res = LivenessResult{Status: hypersql.HealthStatusUnhealthy, Error: err.Error()}
+		}
+		resp.Status = resp.Status.Worse(res.Status)
+		resp.Databases[name] = res
+	}
+	writeJSON(w, statusCode(resp.Status), resp)
+}
+
+func (h *Handler) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	dbs := h.snapshot()
+	if name := r.PathValue("name"); len(name) > 0 {
+		db, ok := dbs[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		dbs = map[string]Database{name: db}
+	}
+
+	resp := ReadinessResponse{
+		Status:    hypersql.HealthStatusHealthy,
+		Databases: make(map[string]*hypersql.HealthReport),
+	}
+	for name, db := range dbs {
+		report := db.HealthReport(ctx)
+		resp.Status = resp.Status.Worse(report.Status)
+		resp.Databases[name] = report
+	}
+	writeJSON(w, statusCode(resp.Status), resp)
+}
+
+func (h *Handler) serveStats(w http.ResponseWriter, r *http.Request) {
+	resp := make(map[string]hypersql.PoolStats)
+	for name, db := range h.snapshot() {
+		if s, ok := db.(withStats); ok {
+			resp[name] = hypersql.NewPoolStats(s.Stats())
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) serveConfig(w http.ResponseWriter, r *http.Request) {
+	resp := make(map[string]*hypersql.Config)
+	for name, db := range h.snapshot() {
+		if c, ok := db.(withConfig); ok {
+			resp[name] = c.Config().Redacted()
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) snapshot() map[string]Database {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return maps.Clone(h.dbs)
+}
+
+func statusCode(s hypersql.HealthStatus) int {
+	if s.Healthy() {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
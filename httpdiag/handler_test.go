@@ -0,0 +1,153 @@
+//go:build sqlite
+
+package httpdiag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blink-io/hypersql"
+	"github.com/blink-io/hypersql/sqlite"
+	sqliteparams "github.com/blink-io/hypersql/sqlite/params"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openSQLite(t *testing.T, name string) *hypersql.DB {
+	c := &hypersql.Config{
+		Dialect:  hypersql.DialectSQLite,
+		Name:     "file:" + name,
+		Password: "secret",
+		Params: hypersql.ConfigParams{
+			sqliteparams.ConnParams.Cache: sqlite.CacheShared,
+			sqliteparams.ConnParams.Mode:  sqlite.ModeMemory,
+		},
+		ValidationSQL: "SELECT 1",
+	}
+	db, err := hypersql.Open(context.Background(), c)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return db
+}
+
+func doGet(t *testing.T, srv *httptest.Server, path string, v any) int {
+	resp, err := srv.Client().Get(srv.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode != http.StatusNotFound {
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+func TestHandler(t *testing.T) {
+	h := New(ExposeStats(), ExposeConfig())
+	h.Register("main", openSQLite(t, "main.db"))
+	h.Register("audit", openSQLite(t, "audit.db"))
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	t.Run("liveness", func(t *testing.T) {
+		var resp LivenessResponse
+		assert.Equal(t, http.StatusOK, doGet(t, srv, PathLiveness, &resp))
+		assert.Equal(t, hypersql.HealthStatusHealthy, resp.Status)
+		assert.Len(t, resp.Databases, 2)
+	})
+
+	t.Run("readiness", func(t *testing.T) {
+		var resp ReadinessResponse
+		assert.Equal(t, http.StatusOK, doGet(t, srv, PathReadiness, &resp))
+		assert.Equal(t, hypersql.HealthStatusHealthy, resp.Status)
+		require.Contains(t, resp.Databases, "main")
+		assert.NotEmpty(t, resp.Databases["main"].ServerVersion)
+	})
+
+	t.Run("readiness by name", func(t *testing.T) {
+		var resp ReadinessResponse
+		assert.Equal(t, http.StatusOK, doGet(t, srv, PathReadiness+"/audit", &resp))
+		assert.Len(t, resp.Databases, 1)
+		assert.Equal(t, http.StatusNotFound, doGet(t, srv, PathReadiness+"/none", nil))
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		var resp map[string]hypersql.PoolStats
+		assert.Equal(t, http.StatusOK, doGet(t, srv, PathStats, &resp))
+		assert.Len(t, resp, 2)
+	})
+
+	t.Run("config", func(t *testing.T) {
+		var resp map[string]*hypersql.Config
+		assert.Equal(t, http.StatusOK, doGet(t, srv, PathConfig, &resp))
+		require.Contains(t, resp, "main")
+		assert.Equal(t, hypersql.RedactedSecret, resp["main"].Password)
+	})
+}
+
+// configDB serves c as the config of DB.
+type configDB struct {
+	*hypersql.DB
+	c *hypersql.Config
+}
+
+func (d configDB) Config() *hypersql.Config {
+	return d.c
+}
+
+func TestHandler_ConfigSecrets(t *testing.T) {
+	c := &hypersql.Config{
+		Dialect:  hypersql.DialectMySQL,
+		Host:     "localhost",
+		Name:     "app",
+		Password: "s3cr3t",
+		Params: hypersql.ConfigParams{
+			"sslpassword": "k3ypass",
+			"_auth_pass":  "k3ypass",
+			"authPass":    "k3ypass",
+			"replica_dsn": "app:k3ypass@tcp(replica:3306)/app",
+			"charset":     "utf8mb4",
+		},
+	}
+	h := New(ExposeConfig())
+	h.Register("main", configDB{DB: openSQLite(t, "secrets.db"), c: c})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	var resp map[string]*hypersql.Config
+	assert.Equal(t, http.StatusOK, doGet(t, srv, PathConfig, &resp))
+	require.Contains(t, resp, "main")
+	rc := resp["main"]
+	assert.Equal(t, hypersql.RedactedSecret, rc.Password)
+	assert.Equal(t, hypersql.RedactedSecret, rc.Params["sslpassword"])
+	assert.Equal(t, hypersql.RedactedSecret, rc.Params["_auth_pass"])
+	assert.Equal(t, hypersql.RedactedSecret, rc.Params["authPass"])
+	assert.Equal(t, "app:"+hypersql.RedactedSecret+"@tcp(replica:3306)/app", rc.Params["replica_dsn"])
+	assert.Equal(t, "utf8mb4", rc.Params["charset"])
+}
+
+func TestHandler_Unavailable(t *testing.T) {
+	db := openSQLite(t, "closed.db")
+	require.NoError(t, db.Close())
+
+	h := New()
+	h.Register("closed", db)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	var live LivenessResponse
+	assert.Equal(t, http.StatusServiceUnavailable, doGet(t, srv, PathLiveness, &live))
+	assert.Equal(t, hypersql.HealthStatusUnhealthy, live.Databases["closed"].Status)
+	assert.NotEmpty(t, live.Databases["closed"].Error)
+
+	var ready ReadinessResponse
+	assert.Equal(t, http.StatusServiceUnavailable, doGet(t, srv, PathReadiness, &ready))
+	assert.Equal(t, hypersql.HealthStatusUnhealthy, ready.Status)
+
+	assert.Equal(t, http.StatusNotFound, doGet(t, srv, PathStats, nil))
+}
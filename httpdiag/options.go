@@ -0,0 +1,28 @@
+package httpdiag
+
+import (
+	"time"
+)
+
+type Option func(*Handler)
+
+// Timeout bounds the health checks of each request.
+func Timeout(d time.Duration) Option {
+	return func(h *Handler) {
+		h.timeout = d
+	}
+}
+
+// ExposeStats serves the pool stats of the databases on /stats.
+func ExposeStats() Option {
+	return func(h *Handler) {
+		h.exposeStats = true
+	}
+}
+
+// ExposeConfig serves the redacted configs of the databases on /config.
+func ExposeConfig() Option {
+	return func(h *Handler) {
+		h.exposeConfig = true
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"runtime"
 )
 
 const (
@@ -61,34 +60,54 @@ type (
 )
 
 func NewSqlDB(c *Config) (*sql.DB, error) {
+	return newSqlDB(context.Background(), c)
+}
+
+func newSqlDB(ctx context.Context, c *Config) (*sql.DB, error) {
+	c, err := c.ResolveSecrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.validateForOpen(ctx); err != nil {
+		return nil, err
+	}
+
 	dialect := GetFormalDialect(c.Dialect)
 	connFn := GetConnector(dialect)
 	if connFn == nil {
 		return nil, ErrUnsupportedDialect
 	}
 
-	ctx := context.Background()
 	conn, err := connFn(ctx, c)
 	if err != nil {
 		return nil, err
 	}
 
 	db := sql.OpenDB(conn)
+	// The connector is closed by the DB, e.g. the registered TLS configs of MySQL.
+	if err := setupSqlDB(ctx, db, c); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
 
+// setupSqlDB pings the new DB, runs the handlers and init SQLs and configures the pool.
+func setupSqlDB(ctx context.Context, db *sql.DB, c *Config) error {
 	// Do ping check
 	if err := DoPingContext(ctx, db); err != nil {
-		return nil, err
+		return err
 	}
 
 	for _, h := range c.AfterHandlers {
-		if err = h(ctx, db); err != nil {
-			return nil, fmt.Errorf("sql.DB can not be handle, reason:%s", err.Error())
+		if err := h(ctx, db); err != nil {
+			return fmt.Errorf("sql.DB can not be handle, reason:%s", err.Error())
 		}
 	}
 
 	var doExec = func(action string, sql string) error {
 		if len(sql) > 0 {
-			if _, err := db.Exec(sql); err != nil {
+			if _, err := db.ExecContext(ctx, sql); err != nil {
 				return fmt.Errorf("unable to exec sql for [%s]: %s, reason: %s", action, sql, err)
 			}
 		}
@@ -96,36 +115,40 @@ func NewSqlDB(c *Config) (*sql.DB, error) {
 	}
 
 	if err := doExec("connection initialization", c.ConnInitSQL); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := doExec("validation", c.ValidationSQL); err != nil {
-		return nil, err
+		return err
 	}
 
-	// Reference: https://bun.uptrace.dev/guide/running-bun-in-production.html
-	maxIdleConns := c.MaxIdleConns
+	maxOpenConns := configurePool(ctx, db, c)
+
+	if n := min(max(c.WarmUpConns, c.MinIdleConns), maxOpenConns); n > 0 {
+		if err := warmUpPool(ctx, db, n); err != nil {
+			return fmt.Errorf("unable to warm up pool: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// configurePool applies the pool settings of config to db, and returns the resolved MaxOpenConns.
+// Reference: https://bun.uptrace.dev/guide/running-bun-in-production.html
+func configurePool(ctx context.Context, db *sql.DB, c *Config) int {
 	maxOpenConns := c.MaxOpenConns
-	connMaxLifetime := c.ConnMaxLifetime
-	connMaxIdleTime := c.ConnMaxIdleTime
-	if maxOpenConns > 0 {
-		db.SetMaxOpenConns(maxOpenConns)
-	} else {
-		// TODO In Docker how we should do?
-		maxOpenConns = 4 * runtime.GOMAXPROCS(0)
-		db.SetMaxOpenConns(maxOpenConns)
+	if maxOpenConns <= 0 {
+		// The dialect default or 4 connections per CPU available in the container
+		maxOpenConns = defaultMaxOpenConns(ctx, db, c)
 	}
-	if maxIdleConns > 0 {
+	db.SetMaxOpenConns(maxOpenConns)
+	if maxIdleConns := c.MaxIdleConns; maxIdleConns > 0 {
 		db.SetMaxIdleConns(maxIdleConns)
 	} else {
 		db.SetMaxIdleConns(maxOpenConns)
 	}
-	if connMaxIdleTime > 0 {
-		db.SetConnMaxIdleTime(connMaxIdleTime)
-	}
-	if connMaxLifetime > 0 {
-		db.SetConnMaxLifetime(connMaxLifetime)
-	}
-
-	return db, nil
+	// Zero means no limit
+	db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	return maxOpenConns
 }
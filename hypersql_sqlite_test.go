@@ -16,6 +16,7 @@ import (
 	sqliteparams "github.com/blink-io/hypersql/sqlite/params"
 	"github.com/qustavo/sqlhooks/v2/hooks/loghooks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	_ "modernc.org/sqlite"
 )
 
@@ -71,3 +72,90 @@ func TestSqlite_Driver_1(t *testing.T) {
 		assert.Nil(t, db)
 	})
 }
+
+func TestSqlite_Open(t *testing.T) {
+	ctx := context.Background()
+	var closed []string
+	c := &Config{
+		Dialect: DialectSQLite,
+		Name:    "file:open.db",
+		Params: ConfigParams{
+			sqliteparams.ConnParams.Cache: sqlite.CacheShared,
+			sqliteparams.ConnParams.Mode:  sqlite.ModeMemory,
+		},
+		ValidationSQL: "SELECT 1",
+		CloseHandlers: CloseHandlers{
+			func(ctx context.Context, db *sql.DB) error {
+				closed = append(closed, "first")
+				return nil
+			},
+			func(ctx context.Context, db *sql.DB) error {
+				closed = append(closed, "second")
+				return nil
+			},
+		},
+	}
+
+	db, err := Open(ctx, c)
+	require.NoError(t, err)
+
+	info := db.DBInfo()
+	assert.Equal(t, DialectSQLite, info.Dialect)
+	assert.NotEmpty(t, info.Version)
+	assert.Same(t, db.DB, db.SqlDB())
+	assert.NoError(t, db.HealthCheck(ctx))
+
+	require.NoError(t, db.Close())
+	require.NoError(t, db.Close())
+	assert.Equal(t, []string{"second", "first"}, closed)
+	assert.Error(t, db.HealthCheck(ctx))
+}
+
+func TestSqlite_HealthReport(t *testing.T) {
+	ctx := context.Background()
+	c := &Config{
+		Dialect: DialectSQLite,
+		Name:    "file:health.db",
+		Params: ConfigParams{
+			sqliteparams.ConnParams.Cache: sqlite.CacheShared,
+			sqliteparams.ConnParams.Mode:  sqlite.ModeMemory,
+		},
+		ValidationSQL: "SELECT 1",
+		MaxOpenConns:  2,
+	}
+
+	db, err := Open(ctx, c)
+	require.NoError(t, err)
+	defer db.Close()
+
+	r := db.HealthReport(ctx)
+	assert.Equal(t, HealthStatusHealthy, r.Status)
+	assert.Equal(t, db.DBInfo().Version, r.ServerVersion)
+	assert.False(t, r.Validation.Skipped)
+	assert.False(t, r.ReadOnly.ReadOnly)
+	assert.Equal(t, 2, r.Pool.MaxOpenConnections)
+
+	c.HealthThresholds = &HealthThresholds{InUseRatioDegraded: 0.5}
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	r = db.HealthReport(ctx)
+	assert.Equal(t, 1, r.Pool.InUse)
+	assert.Equal(t, HealthStatusDegraded, r.Pool.Status)
+	assert.Equal(t, HealthStatusDegraded, r.Status)
+
+	// A saturated pool is degraded by default.
+	conn2, err := db.Conn(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, HealthStatusDegraded, db.poolHealth(DefaultHealthThresholds).Status)
+	require.NoError(t, conn2.Close())
+
+	// The next query is used when one fails.
+	queries := readOnlyQueries[DialectSQLite]
+	defer func() { readOnlyQueries[DialectSQLite] = queries }()
+	readOnlyQueries[DialectSQLite] = []string{"SELECT @@global.super_read_only", "PRAGMA query_only"}
+	ro := db.readOnlyHealth(ctx, DefaultHealthThresholds, true)
+	assert.Equal(t, HealthStatusHealthy, ro.Status)
+	assert.Empty(t, ro.Error)
+}
@@ -4,6 +4,8 @@ type (
 	DBInfo struct {
 		Name    string
 		Dialect string
+		// Version is the server version, it is only detected by Open.
+		Version string
 	}
 
 	WithDBInfo interface {
@@ -11,9 +13,15 @@ type (
 	}
 )
 
+// NewDBInfo creates DBInfo from c, the dialect is normalized to the formal one, e.g. pg to postgres,
+// so it can be used to look up the registries of dialects.
 func NewDBInfo(c *Config) DBInfo {
+	dialect := GetFormalDialect(c.Dialect)
+	if len(dialect) == 0 {
+		dialect = c.Dialect
+	}
 	return DBInfo{
 		Name:    c.Name,
-		Dialect: c.Dialect,
+		Dialect: dialect,
 	}
 }
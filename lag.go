@@ -0,0 +1,160 @@
+package hypersql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	ErrReplicationNotRunning = errors.New("replication is not running")
+
+	// lagProbes holds the replication lag probe for each dialect.
+	lagProbes = make(map[string]LagProbe)
+)
+
+type (
+	// LagProbe measures the replication lag of a replica.
+	LagProbe interface {
+		Lag(ctx context.Context, db *sql.DB) (time.Duration, error)
+	}
+
+	LagProbeFunc func(ctx context.Context, db *sql.DB) (time.Duration, error)
+
+	// LagObserver is notified with every lag sample, err is not nil if the sample fails.
+	// It may be called concurrently for different replicas.
+	LagObserver func(replica string, lag time.Duration, err error)
+)
+
+func (f LagProbeFunc) Lag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	return f(ctx, db)
+}
+
+func RegisterLagProbe(dialect string, p LagProbe) {
+	lagProbes[dialect] = p
+}
+
+// GetLagProbe returns the lag probe of the dialect, or nil if the dialect has none.
+func GetLagProbe(dialect string) LagProbe {
+	return lagProbes[dialect]
+}
+
+// secondsLagProbe queries a single nullable value of lag in seconds.
+// No rows means the server is not a replica, NULL means the replication is not running.
+func secondsLagProbe(query string) LagProbe {
+	return LagProbeFunc(func(ctx context.Context, db *sql.DB) (time.Duration, error) {
+		var secs sql.NullFloat64
+		if err := db.QueryRowContext(ctx, query).Scan(&secs); errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+		if !secs.Valid {
+			return 0, ErrReplicationNotRunning
+		}
+		return time.Duration(secs.Float64 * float64(time.Second)), nil
+	})
+}
+
+// WithMaxReplicaLag takes the replicas lagging over d out of rotation.
+// A replica whose lag cannot be sampled is taken out of rotation too.
+func WithMaxReplicaLag(d time.Duration) ReplicaSetOption {
+	return func(rs *ReplicaSet) {
+		rs.maxLag = d
+	}
+}
+
+// WithLagProbe sets the lag probe for every replica instead of the dialect one.
+func WithLagProbe(p LagProbe) ReplicaSetOption {
+	return func(rs *ReplicaSet) {
+		rs.lagProbe = p
+	}
+}
+
+// WithLagObserver sets the observer of lag samples.
+func WithLagObserver(o LagObserver) ReplicaSetOption {
+	return func(rs *ReplicaSet) {
+		rs.lagObserver = o
+	}
+}
+
+// Lag returns the latest sampled replication lag, ok is false if it is not sampled yet or the sample failed.
+func (r *Replica) Lag() (lag time.Duration, ok bool) {
+	v := r.lag.Load()
+	if v < 0 {
+		return 0, false
+	}
+	return time.Duration(v), true
+}
+
+// ReplicaLags returns the latest sampled lags keyed by replica name, unknown lags are absent.
+func (rs *ReplicaSet) ReplicaLags() map[string]time.Duration {
+	lags := make(map[string]time.Duration, len(rs.replicas))
+	for _, r := range rs.replicas {
+		if lag, ok := r.Lag(); ok {
+			lags[r.name] = lag
+		}
+	}
+	return lags
+}
+
+// SampleLags samples the replication lag of every replica immediately.
+func (rs *ReplicaSet) SampleLags(ctx context.Context) {
+	for _, r := range rs.replicas {
+		rs.sampleLag(ctx, r)
+	}
+}
+
+func (rs *ReplicaSet) sampleLag(ctx context.Context, r *Replica) {
+	p := rs.lagProbe
+	if p == nil {
+		p = GetLagProbe(r.info.Dialect)
+	}
+	if p == nil {
+		return
+	}
+	lag, err := p.Lag(ctx, r.DB.DB)
+	if err != nil {
+		r.lag.Store(-1)
+	} else {
+		r.lag.Store(int64(lag))
+	}
+	if o := rs.lagObserver; o != nil {
+		o(r.name, lag, err)
+	}
+}
+
+// lagging reports whether the replica must be skipped for its lag.
+func (rs *ReplicaSet) lagging(r *Replica) bool {
+	if rs.maxLag <= 0 {
+		return false
+	}
+	if rs.lagProbe == nil && GetLagProbe(r.info.Dialect) == nil {
+		return false
+	}
+	lag, ok := r.Lag()
+	return !ok || lag > rs.maxLag
+}
+
+// RegisterReplicaLagMetrics registers the gauge db.client.replication.lag in seconds for the replicas,
+// the replica name is set as attribute db.replica.
+func RegisterReplicaLagMetrics(rs *ReplicaSet, mp metric.MeterProvider) error {
+	meter := mp.Meter("github.com/blink-io/hypersql")
+	_, err := meter.Float64ObservableGauge("db.client.replication.lag",
+		metric.WithDescription("The replication lag of the replica"),
+		metric.WithUnit("s"),
+		metric.WithFloat64Callback(func(ctx context.Context, o metric.Float64Observer) error {
+			for _, r := range rs.replicas {
+				if lag, ok := r.Lag(); ok {
+					o.Observe(lag.Seconds(), metric.WithAttributes(attribute.String("db.replica", r.name)))
+				}
+			}
+			return nil
+		}),
+	)
+	return err
+}
@@ -0,0 +1,276 @@
+package hypersql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultManagerShutdownTimeout is the timeout of Manager.Close when it is not set by option.
+const DefaultManagerShutdownTimeout = 30 * time.Second
+
+var (
+	ErrManagerClosed = errors.New("manager is closed")
+
+	ErrUnknownDatabase = errors.New("unknown database")
+)
+
+type (
+	ManagerOption func(*Manager)
+
+	// Manager holds the named databases of an application.
+	Manager struct {
+		names   []string
+		entries map[string]*managerEntry
+
+		lazy            bool
+		shutdownTimeout time.Duration
+
+		mu     sync.Mutex
+		opened []string
+		closed bool
+	}
+
+	managerEntry struct {
+		c  *Config
+		mu sync.Mutex
+		db *DB
+	}
+
+	// ManagerHealthReport combines the health reports of the opened databases,
+	// Status is the worst status of them.
+	ManagerHealthReport struct {
+		Status    HealthStatus             `json:"status"`
+		CheckedAt time.Time                `json:"checked_at"`
+		Databases map[string]*HealthReport `json:"databases"`
+	}
+)
+
+// WithLazyOpen opens the databases on the first Get instead of in NewManager.
+func WithLazyOpen() ManagerOption {
+	return func(m *Manager) {
+		m.lazy = true
+	}
+}
+
+// WithShutdownTimeout sets the timeout of Close.
+func WithShutdownTimeout(d time.Duration) ManagerOption {
+	return func(m *Manager) {
+		m.shutdownTimeout = d
+	}
+}
+
+// NewManager creates the Manager of the named configs.
+// The databases are opened in name order unless WithLazyOpen is given,
+// all the opened ones are closed if any of them fails.
+func NewManager(ctx context.Context, configs map[string]*Config, ops ...ManagerOption) (*Manager, error) {
+	m := &Manager{
+		entries:         make(map[string]*managerEntry, len(configs)),
+		shutdownTimeout: DefaultManagerShutdownTimeout,
+	}
+	for _, o := range ops {
+		o(m)
+	}
+	for name, c := range configs {
+		if c == nil {
+			return nil, fmt.Errorf("database %s: %w", name, ErrNilConfig)
+		}
+		m.names = append(m.names, name)
+		m.entries[name] = &managerEntry{c: c}
+	}
+	slices.Sort(m.names)
+
+	if !m.lazy {
+		for _, name := range m.names {
+			if _, err := m.Get(ctx, name); err != nil {
+				_ = m.Close()
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// ParseManagerYAML parses the named configs from YAML, the top level keys are the names.
+// The configs are decoded as ParseConfig does, the field paths of errors start with the names.
+func ParseManagerYAML(data []byte) (map[string]*Config, error) {
+	raws := make(map[string]map[string]any)
+	if err := yaml.Unmarshal(data, &raws); err != nil {
+		return nil, fmt.Errorf("unable to parse manager configs: %w", err)
+	}
+	configs := make(map[string]*Config, len(raws))
+	for name, raw := range raws {
+		if raw == nil {
+			configs[name] = nil
+			continue
+		}
+		c := new(Config)
+		if err := decodeConfig(name, raw, c); err != nil {
+			return nil, err
+		}
+		configs[name] = c
+	}
+	return configs, nil
+}
+
+// LoadManagerYAML loads the named configs from the YAML file.
+func LoadManagerYAML(path string) (map[string]*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseManagerYAML(data)
+}
+
+// Names returns the sorted names of databases.
+func (m *Manager) Names() []string {
+	return slices.Clone(m.names)
+}
+
+// Get returns the database of the name, it is opened if it is not yet.
+// ErrManagerClosed is returned after Close.
+func (m *Manager) Get(ctx context.Context, name string) (*DB, error) {
+	e, ok := m.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDatabase, name)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if m.isClosed() {
+		return nil, ErrManagerClosed
+	}
+	if e.db != nil {
+		return e.db, nil
+	}
+
+	db, err := Open(ctx, e.c)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database %s: %w", name, err)
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		_ = db.Close()
+		return nil, ErrManagerClosed
+	}
+	e.db = db
+	m.opened = append(m.opened, name)
+	m.mu.Unlock()
+	return db, nil
+}
+
+// HealthCheck checks all the opened databases.
+func (m *Manager) HealthCheck(ctx context.Context) error {
+	dbs := m.openedDBs()
+	errs := make([]error, len(dbs))
+	var wg sync.WaitGroup
+	for i, name := range dbs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.entries[name].db.HealthCheck(ctx); err != nil {
+				errs[i] = fmt.Errorf("database %s: %w", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// HealthReport combines the health reports of all the opened databases.
+func (m *Manager) HealthReport(ctx context.Context) *ManagerHealthReport {
+	dbs := m.openedDBs()
+	reports := make([]*HealthReport, len(dbs))
+	var wg sync.WaitGroup
+	for i, name := range dbs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reports[i] = m.entries[name].db.HealthReport(ctx)
+		}()
+	}
+	wg.Wait()
+
+	r := &ManagerHealthReport{
+		Status:    HealthStatusHealthy,
+		CheckedAt: time.Now(),
+		Databases: make(map[string]*HealthReport, len(dbs)),
+	}
+	for i, name := range dbs {
+		r.Databases[name] = reports[i]
+		r.Status = r.Status.Worse(reports[i].Status)
+	}
+	return r
+}
+
+// Close shuts down the opened databases in the reverse order of opening, see DB.Shutdown.
+// When the shutdown timeout is exceeded, it stops waiting and force-closes the remaining databases.
+// It is safe to call Close more than once.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	opened := m.opened
+	m.mu.Unlock()
+
+	ctx := context.Background()
+	if m.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.shutdownTimeout)
+		defer cancel()
+	}
+
+	var errs []error
+	for i := len(opened) - 1; i >= 0; i-- {
+		name := opened[i]
+		done := make(chan error, 1)
+		go func() {
+			done <- m.entries[name].db.Shutdown(ctx)
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				errs = append(errs, fmt.Errorf("database %s: %w", name, err))
+			}
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("unable to close database %s: %w", name, ctx.Err()))
+			// The remaining databases are closed without waiting, their in-flight work is canceled.
+			for j := i - 1; j >= 0; j-- {
+				if err := m.entries[opened[j]].db.Shutdown(ctx); err != nil {
+					errs = append(errs, fmt.Errorf("database %s: %w", opened[j], err))
+				}
+			}
+			return errors.Join(errs...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manager) isClosed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.closed
+}
+
+// openedDBs returns the names of opened databases in name order.
+func (m *Manager) openedDBs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	opened := slices.Clone(m.opened)
+	slices.Sort(opened)
+	return opened
+}
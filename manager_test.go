@@ -0,0 +1,125 @@
+//go:build sqlite
+
+package hypersql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_Eager(t *testing.T) {
+	ctx := context.Background()
+	m, err := NewManager(ctx, map[string]*Config{
+		"users":  newSQLiteTestConfig("manager_users.db"),
+		"orders": newSQLiteTestConfig("manager_orders.db"),
+		"audit":  newSQLiteTestConfig("manager_audit.db"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"audit", "orders", "users"}, m.Names())
+
+	var mu sync.Mutex
+	var closed []string
+	for _, name := range m.Names() {
+		db, err := m.Get(ctx, name)
+		require.NoError(t, err)
+		db.OnClose(func(context.Context, *sql.DB) error {
+			mu.Lock()
+			defer mu.Unlock()
+			closed = append(closed, name)
+			return nil
+		})
+	}
+
+	_, err = m.Get(ctx, "unknown")
+	require.ErrorIs(t, err, ErrUnknownDatabase)
+
+	require.NoError(t, m.HealthCheck(ctx))
+	r := m.HealthReport(ctx)
+	assert.Equal(t, HealthStatusHealthy, r.Status)
+	assert.Len(t, r.Databases, 3)
+	assert.Equal(t, "file:manager_users.db", r.Databases["users"].Name)
+
+	require.NoError(t, m.Close())
+	require.NoError(t, m.Close())
+	assert.Equal(t, []string{"users", "orders", "audit"}, closed)
+
+	_, err = m.Get(ctx, "users")
+	require.ErrorIs(t, err, ErrManagerClosed)
+	assert.Empty(t, m.HealthReport(ctx).Databases)
+}
+
+func TestManager_Lazy(t *testing.T) {
+	ctx := context.Background()
+	m, err := NewManager(ctx, map[string]*Config{
+		"a": newSQLiteTestConfig("manager_lazy_a.db"),
+		"b": newSQLiteTestConfig("manager_lazy_b.db"),
+		"c": {Dialect: "unknown"},
+	}, WithLazyOpen())
+	require.NoError(t, err)
+	defer m.Close()
+
+	assert.Empty(t, m.HealthReport(ctx).Databases)
+
+	var closed []string
+	for _, name := range []string{"b", "a"} {
+		db, err := m.Get(ctx, name)
+		require.NoError(t, err)
+		db.OnClose(func(context.Context, *sql.DB) error {
+			closed = append(closed, name)
+			return nil
+		})
+	}
+	db, err := m.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "file:manager_lazy_a.db", db.DBInfo().Name)
+
+	_, err = m.Get(ctx, "c")
+	require.ErrorIs(t, err, ErrUnsupportedDialect)
+
+	assert.Len(t, m.HealthReport(ctx).Databases, 2)
+	require.NoError(t, m.Close())
+	assert.Equal(t, []string{"a", "b"}, closed)
+
+	_, err = m.Get(ctx, "c")
+	require.ErrorIs(t, err, ErrManagerClosed)
+}
+
+func TestManager_OpenFailed(t *testing.T) {
+	_, err := NewManager(context.Background(), map[string]*Config{
+		"a": newSQLiteTestConfig("manager_failed_a.db"),
+		"b": {Dialect: "unknown"},
+	})
+	require.ErrorIs(t, err, ErrUnsupportedDialect)
+}
+
+func TestManager_ShutdownTimeout(t *testing.T) {
+	ctx := context.Background()
+	m, err := NewManager(ctx, map[string]*Config{
+		"fast": newSQLiteTestConfig("manager_fast.db"),
+		"slow": newSQLiteTestConfig("manager_slow.db"),
+	}, WithShutdownTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	fast, err := m.Get(ctx, "fast")
+	require.NoError(t, err)
+
+	db, err := m.Get(ctx, "slow")
+	require.NoError(t, err)
+	db.OnClose(func(ctx context.Context, _ *sql.DB) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	err = m.Close()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	// The databases after the timed out one are still closed.
+	assert.ErrorContains(t, fast.PingContext(ctx), "database is closed")
+}
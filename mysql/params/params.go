@@ -80,3 +80,30 @@ type connParams struct {
 
 	ParseTime string
 }
+
+func (p connParams) Exists(key string) bool {
+	switch key {
+	case p.Host,
+		p.Port,
+		p.Socket,
+		p.Schema,
+		p.User,
+		p.Password,
+		p.Loc,
+		p.SSLMode,
+		p.SSLCA,
+		p.SSLCAPath,
+		p.SSLCert,
+		p.SSLCRL,
+		p.SSLCrlpath,
+		p.SSLKey,
+		p.TLSVersion,
+		p.AutoMethod,
+		p.Collation,
+		p.Compress,
+		p.ParseTime:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,220 @@
+package hypersql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMinIdleCheckInterval is the interval that DB refills the idle connections to Config.MinIdleConns.
+const DefaultMinIdleCheckInterval = 30 * time.Second
+
+// refillConnTimeout bounds taking each connection when the idle ones are refilled.
+const refillConnTimeout = time.Second
+
+var (
+	// cgroupRoot is where the cgroup filesystem is mounted.
+	cgroupRoot = "/sys/fs/cgroup"
+
+	// poolSizers holds the default MaxOpenConns resolver for each dialect.
+	poolSizers = make(map[string]PoolSizer)
+)
+
+// PoolSizer resolves the default MaxOpenConns of the dialect, db is connected but its pool is not configured yet.
+// A non-positive value means no preference, and the CPU based default is used.
+type PoolSizer func(ctx context.Context, db *sql.DB, c *Config) int
+
+func RegisterPoolSizer(dialect string, s PoolSizer) {
+	poolSizers[dialect] = s
+}
+
+// AvailableCPUs returns the number of CPUs that the process is able to use,
+// it is GOMAXPROCS bounded by the cgroup v1/v2 CPU quota.
+func AvailableCPUs() int {
+	cpus := runtime.GOMAXPROCS(0)
+	if quota, ok := cgroupCPUQuota(cgroupRoot); ok {
+		cpus = min(cpus, max(1, int(math.Ceil(quota))))
+	}
+	return cpus
+}
+
+// cgroupCPUQuota reads the CPU quota in CPUs, ok is false if there is no quota.
+func cgroupCPUQuota(root string) (float64, bool) {
+	// cgroup v2: "$MAX $PERIOD", $MAX is "max" for no quota.
+	if b, err := os.ReadFile(filepath.Join(root, "cpu.max")); err == nil {
+		fields := strings.Fields(string(b))
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0, false
+		}
+		return parseCPUQuota(fields[0], fields[1])
+	}
+	// cgroup v1: cfs_quota_us is -1 for no quota.
+	for _, dir := range []string{"cpu", "cpu,cpuacct"} {
+		quota, err := os.ReadFile(filepath.Join(root, dir, "cpu.cfs_quota_us"))
+		if err != nil {
+			continue
+		}
+		period, err := os.ReadFile(filepath.Join(root, dir, "cpu.cfs_period_us"))
+		if err != nil {
+			continue
+		}
+		return parseCPUQuota(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+	}
+	return 0, false
+}
+
+func parseCPUQuota(quota, period string) (float64, bool) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return q / p, true
+}
+
+// defaultMaxOpenConns returns the dialect default if it is resolved, otherwise 4 connections per available CPU.
+func defaultMaxOpenConns(ctx context.Context, db *sql.DB, c *Config) int {
+	if s, ok := poolSizers[GetFormalDialect(c.Dialect)]; ok {
+		if n := s(ctx, db, c); n > 0 {
+			return n
+		}
+	}
+	return 4 * AvailableCPUs()
+}
+
+// fixedPoolSizer always returns n.
+func fixedPoolSizer(n int) PoolSizer {
+	return func(context.Context, *sql.DB, *Config) int {
+		return n
+	}
+}
+
+// serverMaxConnsPoolSizer divides the max connections of server queried by query among Config.ExpectedReplicas,
+// bounded by the CPU based default.
+func serverMaxConnsPoolSizer(query string) PoolSizer {
+	return func(ctx context.Context, db *sql.DB, c *Config) int {
+		var maxConns int
+		if err := db.QueryRowContext(ctx, query).Scan(&maxConns); err != nil || maxConns <= 0 {
+			return 0
+		}
+		replicas := max(1, c.ExpectedReplicas)
+		return max(1, min(4*AvailableCPUs(), maxConns/replicas))
+	}
+}
+
+// warmUpPool opens n connections at once and returns them to the pool as idle ones.
+func warmUpPool(ctx context.Context, db *sql.DB, n int) error {
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+
+	var errs []error
+	for range n {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		conns = append(conns, conn)
+		if err := conn.PingContext(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// idleKeeper refills the idle connections of DB to minIdle periodically.
+type idleKeeper struct {
+	db       *sql.DB
+	minIdle  int
+	interval time.Duration
+
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// keptIdleConns returns Config.MinIdleConns bounded by Config.MaxIdleConns,
+// the pool closes the idle connections over it right after they are refilled.
+func keptIdleConns(c *Config) int {
+	if c.MaxIdleConns > 0 {
+		return min(c.MinIdleConns, c.MaxIdleConns)
+	}
+	return c.MinIdleConns
+}
+
+func newIdleKeeper(db *sql.DB, minIdle int, interval time.Duration) *idleKeeper {
+	k := &idleKeeper{db: db, minIdle: minIdle, interval: interval, stop: make(chan struct{})}
+	k.wg.Add(1)
+	go k.loop()
+	return k
+}
+
+func (k *idleKeeper) loop() {
+	defer k.wg.Done()
+	ticker := time.NewTicker(k.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-k.stop:
+			return
+		case <-ticker.C:
+			k.refill()
+		}
+	}
+}
+
+// refill opens the missing idle connections in the free slots of the pool only.
+// The pool hands out the idle connections first, so they are held until the new ones are opened,
+// but each is taken with a short timeout, and all are released once a request waits for the pool.
+func (k *idleKeeper) refill() {
+	stats := k.db.Stats()
+	missing := k.minIdle - stats.Idle
+	if stats.MaxOpenConnections > 0 {
+		missing = min(missing, stats.MaxOpenConnections-stats.OpenConnections)
+	}
+	if missing <= 0 {
+		return
+	}
+
+	var conns []*sql.Conn
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+	timeout := min(k.interval, refillConnTimeout)
+	for range stats.Idle + missing {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		conn, err := k.db.Conn(ctx)
+		if err == nil {
+			err = conn.PingContext(ctx)
+			conns = append(conns, conn)
+		}
+		cancel()
+		if err != nil || k.db.Stats().WaitCount > stats.WaitCount {
+			return
+		}
+	}
+}
+
+func (k *idleKeeper) Stop() {
+	k.stopOnce.Do(func() {
+		close(k.stop)
+	})
+	k.wg.Wait()
+}
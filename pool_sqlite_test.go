@@ -0,0 +1,83 @@
+//go:build sqlite
+
+package hypersql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqlite_DefaultPool(t *testing.T) {
+	db, err := Open(context.Background(), newSQLiteTestConfig("pool_default.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, 1, db.Stats().MaxOpenConnections)
+}
+
+func TestServerMaxConnsPoolSizer(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, newSQLiteTestConfig("pool_sizer.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	old := cgroupRoot
+	defer func() { cgroupRoot = old }()
+	cgroupRoot = writeCgroupFiles(t, map[string]string{"cpu.max": "max 100000\n"})
+	cpuDefault := 4 * AvailableCPUs()
+
+	sizer := serverMaxConnsPoolSizer("SELECT 8")
+	assert.Equal(t, min(cpuDefault, 8), sizer(ctx, db.DB, &Config{}))
+	assert.Equal(t, min(cpuDefault, 2), sizer(ctx, db.DB, &Config{ExpectedReplicas: 4}))
+	assert.Equal(t, 1, sizer(ctx, db.DB, &Config{ExpectedReplicas: 100}))
+
+	assert.Zero(t, serverMaxConnsPoolSizer("SELECT NULL")(ctx, db.DB, &Config{}))
+}
+
+func TestSqlite_WarmUp(t *testing.T) {
+	c := newSQLiteTestConfig("pool_warm_up.db")
+	c.MaxOpenConns = 4
+	c.WarmUpConns = 3
+	db, err := Open(context.Background(), c)
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, 3, db.Stats().Idle)
+	assert.Nil(t, db.idleKeeper)
+}
+
+func TestSqlite_MinIdleConns(t *testing.T) {
+	c := newSQLiteTestConfig("pool_min_idle.db")
+	c.MaxOpenConns = 4
+	c.MinIdleConns = 2
+	db, err := Open(context.Background(), c)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NotNil(t, db.idleKeeper)
+	assert.Equal(t, 2, db.Stats().Idle)
+
+	// Drop the idle connections, they are refilled by the keeper.
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(4)
+	require.Zero(t, db.Stats().Idle)
+	db.idleKeeper.refill()
+	assert.Equal(t, 2, db.Stats().Idle)
+
+	// The keeper never takes the connections in use.
+	for range 3 {
+		conn, err := db.Conn(context.Background())
+		require.NoError(t, err)
+		defer conn.Close()
+	}
+	db.idleKeeper.refill()
+	assert.Equal(t, 1, db.Stats().Idle)
+	assert.Equal(t, 4, db.Stats().OpenConnections)
+
+	// The pool closes the idle connections over MaxIdleConns.
+	assert.Equal(t, 2, keptIdleConns(&Config{MinIdleConns: 3, MaxIdleConns: 2}))
+	assert.Equal(t, 3, keptIdleConns(&Config{MinIdleConns: 3}))
+}
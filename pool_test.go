@@ -0,0 +1,67 @@
+package hypersql
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCgroupFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return root
+}
+
+func TestCgroupCPUQuota(t *testing.T) {
+	cases := []struct {
+		name  string
+		files map[string]string
+		quota float64
+		ok    bool
+	}{
+		{name: "v2", files: map[string]string{"cpu.max": "150000 100000\n"}, quota: 1.5, ok: true},
+		{name: "v2 no quota", files: map[string]string{"cpu.max": "max 100000\n"}},
+		{name: "v1", files: map[string]string{
+			"cpu/cpu.cfs_quota_us":  "200000\n",
+			"cpu/cpu.cfs_period_us": "100000\n",
+		}, quota: 2, ok: true},
+		{name: "v1 cpuacct", files: map[string]string{
+			"cpu,cpuacct/cpu.cfs_quota_us":  "50000\n",
+			"cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+		}, quota: 0.5, ok: true},
+		{name: "v1 no quota", files: map[string]string{
+			"cpu/cpu.cfs_quota_us":  "-1\n",
+			"cpu/cpu.cfs_period_us": "100000\n",
+		}},
+		{name: "none"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			quota, ok := cgroupCPUQuota(writeCgroupFiles(t, tc.files))
+			assert.Equal(t, tc.ok, ok)
+			assert.InDelta(t, tc.quota, quota, 0.001)
+		})
+	}
+}
+
+func TestAvailableCPUs(t *testing.T) {
+	old := cgroupRoot
+	defer func() { cgroupRoot = old }()
+
+	cgroupRoot = writeCgroupFiles(t, map[string]string{"cpu.max": "50000 100000\n"})
+	assert.Equal(t, 1, AvailableCPUs())
+
+	cgroupRoot = writeCgroupFiles(t, map[string]string{"cpu.max": "max 100000\n"})
+	assert.Equal(t, runtime.GOMAXPROCS(0), AvailableCPUs())
+
+	cgroupRoot = writeCgroupFiles(t, map[string]string{"cpu.max": "100000000 100000\n"})
+	assert.Equal(t, runtime.GOMAXPROCS(0), AvailableCPUs())
+}
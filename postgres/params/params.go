@@ -36,6 +36,7 @@ var ConnParams = connParams{
 	Service:                 "service",
 	ConnectTimeout:          "connect_timeout",
 	LoadBalanceHosts:        "load_balance_hosts",
+	TargetSessionAttrs:      "target_session_attrs",
 }
 
 // RuntimeParams specifies runtime params.
@@ -142,6 +143,10 @@ type connParams struct {
 
 	// Controls the order in which the client tries to connect to the available hosts and addresses.
 	LoadBalanceHosts string
+
+	// TargetSessionAttrs determines whether the session must have certain properties to be acceptable.
+	// Values: any (default)|read-write|read-only|primary|standby|prefer-standby
+	TargetSessionAttrs string
 }
 
 func (p connParams) Exists(key string) bool {
@@ -175,7 +180,8 @@ func (p connParams) Exists(key string) bool {
 		p.SSLCRLDir,
 		p.Service,
 		p.ConnectTimeout,
-		p.LoadBalanceHosts:
+		p.LoadBalanceHosts,
+		p.TargetSessionAttrs:
 		return true
 	default:
 		return false
@@ -188,11 +194,7 @@ type runtimeParams struct {
 }
 
 func (p runtimeParams) Exists(key string) bool {
-	switch key := strings.ToLower(key); key {
-	case p.TimeZone,
-		p.ClientEncoding:
-		return true
-	default:
-		return false
-	}
+	// The names of runtime params are case-insensitive, e.g. TimeZone.
+	return strings.EqualFold(key, p.TimeZone) ||
+		strings.EqualFold(key, p.ClientEncoding)
 }
@@ -0,0 +1,376 @@
+package hypersql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultReloadInterval is the interval that Reloadable polls its config source when it is not set by option.
+	DefaultReloadInterval = 30 * time.Second
+
+	// DefaultDrainTimeout bounds the shutdown of the old DB after a reconnect when it is not set by option.
+	DefaultDrainTimeout = 30 * time.Second
+
+	// ReloadKindPool means only the pool settings are changed and applied in place.
+	ReloadKindPool ReloadKind = "pool"
+
+	// ReloadKindReconnect means the connection settings are changed and a new DB is swapped in.
+	ReloadKindReconnect ReloadKind = "reconnect"
+)
+
+var ErrReloadableClosed = errors.New("reloadable is closed")
+
+var _ IDB = (*Reloadable)(nil)
+
+type (
+	ReloadKind string
+
+	// ConfigSource provides the latest config for Reloadable.
+	ConfigSource interface {
+		Load(ctx context.Context) (*Config, error)
+	}
+
+	ConfigSourceFunc func(ctx context.Context) (*Config, error)
+
+	// ReloadEvent reports a reload that changes the config.
+	ReloadEvent struct {
+		Kind   ReloadKind
+		Config *Config
+		// Err is the failure of the reload, the current DB and config are kept if it is set.
+		Err error
+		// DrainErr is the failure of shutting down the old DB after it is swapped out.
+		// The old DB is drained in the background, the event of a reconnect is reported when it is done.
+		DrainErr error
+	}
+
+	// ReloadHook is called with every reload event, the config of event must not be modified.
+	ReloadHook func(ReloadEvent)
+
+	ReloadableOption func(*Reloadable)
+
+	// Reloadable is a DB whose config is reloaded from a ConfigSource.
+	// Pool-only changes are applied in place, other changes open a new DB which is swapped in
+	// after it passes the health check, and the old one is drained by DB.Shutdown in the background.
+	// The configs are compared with their secrets resolved, see Config.ResolveSecrets.
+	Reloadable struct {
+		source ConfigSource
+
+		interval     time.Duration
+		drainTimeout time.Duration
+		hook         ReloadHook
+
+		db  atomic.Pointer[DB]
+		cfg atomic.Pointer[Config]
+
+		// mu serializes the reloads.
+		mu     sync.Mutex
+		closed bool
+		// resolved is the applied config whose secrets are resolved, the reloads are compared with it
+		// so that a rotated secret behind an unchanged reference reconnects too.
+		resolved  *Config
+		stop      chan struct{}
+		wg        sync.WaitGroup
+		drains    sync.WaitGroup
+		closeOnce sync.Once
+	}
+
+	fileConfigSource struct {
+		path string
+	}
+)
+
+func (f ConfigSourceFunc) Load(ctx context.Context) (*Config, error) {
+	return f(ctx)
+}
+
+func (k ReloadKind) String() string {
+	return string(k)
+}
+
+// FileConfigSource loads the config from a YAML, JSON or TOML file, see LoadConfig.
+func FileConfigSource(path string) ConfigSource {
+	return &fileConfigSource{path: path}
+}
+
+func (s *fileConfigSource) Load(_ context.Context) (*Config, error) {
+	return LoadConfig(s.path)
+}
+
+// WithReloadInterval sets the interval of polling the config source, zero disables polling.
+func WithReloadInterval(d time.Duration) ReloadableOption {
+	return func(r *Reloadable) {
+		r.interval = d
+	}
+}
+
+// WithDrainTimeout sets the timeout of shutting down the old DB after a reconnect.
+func WithDrainTimeout(d time.Duration) ReloadableOption {
+	return func(r *Reloadable) {
+		r.drainTimeout = d
+	}
+}
+
+// WithReloadHook sets the hook of reload events.
+func WithReloadHook(h ReloadHook) ReloadableOption {
+	return func(r *Reloadable) {
+		r.hook = h
+	}
+}
+
+// NewReloadable loads the config from the source, opens the DB and starts polling the source.
+func NewReloadable(ctx context.Context, source ConfigSource, ops ...ReloadableOption) (*Reloadable, error) {
+	r := &Reloadable{
+		source:       source,
+		interval:     DefaultReloadInterval,
+		drainTimeout: DefaultDrainTimeout,
+		stop:         make(chan struct{}),
+	}
+	for _, o := range ops {
+		o(r)
+	}
+
+	c, err := source.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load config: %w", err)
+	}
+	if r.resolved, err = c.ResolveSecrets(ctx); err != nil {
+		return nil, err
+	}
+	db, err := Open(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	r.db.Store(db)
+	r.cfg.Store(c)
+
+	if r.interval > 0 {
+		r.wg.Add(1)
+		go r.reloadLoop()
+	}
+	return r, nil
+}
+
+// DB returns the current DB. It is shut down after it is swapped out, so do not hold it for long.
+func (r *Reloadable) DB() *DB {
+	return r.db.Load()
+}
+
+// Config returns the latest applied config.
+func (r *Reloadable) Config() *Config {
+	return r.cfg.Load()
+}
+
+// Reload loads the config from the source and applies the changes immediately.
+func (r *Reloadable) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ErrReloadableClosed
+	}
+
+	c, err := r.source.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load config: %w", err)
+	}
+	if c == nil {
+		return ErrNilConfig
+	}
+
+	rc, err := c.ResolveSecrets(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case equalConfig(r.resolved, rc, false):
+		return nil
+	case equalConfig(r.resolved, rc, true):
+		r.db.Load().applyPool(ctx, c)
+		r.cfg.Store(c)
+		r.resolved = rc
+		r.report(ReloadEvent{Kind: ReloadKindPool, Config: c})
+		return nil
+	default:
+		return r.reconnect(ctx, c, rc)
+	}
+}
+
+func (r *Reloadable) reconnect(ctx context.Context, c, resolved *Config) error {
+	db, err := Open(ctx, c)
+	if err == nil {
+		if err = db.HealthCheck(ctx); err != nil {
+			_ = db.Close()
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("unable to reconnect: %w", err)
+		r.report(ReloadEvent{Kind: ReloadKindReconnect, Config: c, Err: err})
+		return err
+	}
+
+	old := r.db.Swap(db)
+	r.cfg.Store(c)
+	r.resolved = resolved
+
+	// The old DB is drained in the background, so the reloads are not blocked by its long operations.
+	r.drains.Add(1)
+	go func() {
+		defer r.drains.Done()
+		drainCtx, cancel := context.WithTimeout(context.Background(), r.drainTimeout)
+		defer cancel()
+		drainErr := old.Shutdown(drainCtx)
+		r.report(ReloadEvent{Kind: ReloadKindReconnect, Config: c, DrainErr: drainErr})
+	}()
+	return nil
+}
+
+func (r *Reloadable) report(e ReloadEvent) {
+	if h := r.hook; h != nil {
+		h(e)
+	}
+}
+
+func (r *Reloadable) reloadLoop() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			// The failures are reported by hook.
+			_ = r.Reload(context.Background())
+		}
+	}
+}
+
+func (r *Reloadable) Begin() (*sql.Tx, error) {
+	return r.DB().Begin()
+}
+
+func (r *Reloadable) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return r.DB().BeginTx(ctx, opts)
+}
+
+func (r *Reloadable) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return r.DB().ExecContext(ctx, query, args...)
+}
+
+func (r *Reloadable) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return r.DB().PrepareContext(ctx, query)
+}
+
+func (r *Reloadable) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return r.DB().QueryContext(ctx, query, args...)
+}
+
+func (r *Reloadable) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return r.DB().QueryRowContext(ctx, query, args...)
+}
+
+func (r *Reloadable) HealthCheck(ctx context.Context) error {
+	return r.DB().HealthCheck(ctx)
+}
+
+func (r *Reloadable) HealthReport(ctx context.Context) *HealthReport {
+	return r.DB().HealthReport(ctx)
+}
+
+// Close stops polling the config source, closes the current DB and waits for the old ones to be drained.
+func (r *Reloadable) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		r.wg.Wait()
+		r.mu.Lock()
+		r.closed = true
+		err = r.DB().Close()
+		r.mu.Unlock()
+		r.drains.Wait()
+	})
+	return err
+}
+
+// poolConfigFields are the fields that can be applied to *sql.DB in place.
+var poolConfigFields = []string{"MaxOpenConns", "MaxIdleConns", "MinIdleConns", "ConnMaxLifetime", "ConnMaxIdleTime"}
+
+// equalConfig compares the configs, the pool fields are ignored if ignorePool is true.
+// Funcs such as hooks are equal if they are the same func.
+func equalConfig(a, b *Config, ignorePool bool) bool {
+	ac, bc := *a, *b
+	if ignorePool {
+		for _, name := range poolConfigFields {
+			reflect.ValueOf(&ac).Elem().FieldByName(name).SetZero()
+			reflect.ValueOf(&bc).Elem().FieldByName(name).SetZero()
+		}
+	}
+	return equalValue(reflect.ValueOf(ac), reflect.ValueOf(bc), 0)
+}
+
+// equalValue is like reflect.DeepEqual, but funcs are compared by pointer and nil slices and maps equal empty ones.
+func equalValue(a, b reflect.Value, depth int) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	if depth > 16 {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Func:
+		return a.Pointer() == b.Pointer()
+	case reflect.Pointer:
+		if a.Pointer() == b.Pointer() {
+			return true
+		}
+		if a.IsNil() || b.IsNil() {
+			return false
+		}
+		return equalValue(a.Elem(), b.Elem(), depth+1)
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return equalValue(a.Elem(), b.Elem(), depth+1)
+	case reflect.Struct:
+		for i := range a.NumField() {
+			if !equalValue(a.Field(i), b.Field(i), depth+1) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := range a.Len() {
+			if !equalValue(a.Index(i), b.Index(i), depth+1) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		iter := a.MapRange()
+		for iter.Next() {
+			bv := b.MapIndex(iter.Key())
+			if !bv.IsValid() || !equalValue(iter.Value(), bv, depth+1) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a.Equal(b)
+	}
+}
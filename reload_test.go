@@ -0,0 +1,202 @@
+//go:build sqlite
+
+package hypersql
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blink-io/hypersql/sqlite"
+	sqliteparams "github.com/blink-io/hypersql/sqlite/params"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConfigSource returns a copy of its config, which is changed by set.
+type testConfigSource struct {
+	mu sync.Mutex
+	c  Config
+}
+
+func (s *testConfigSource) Load(_ context.Context) (*Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.c
+	return &c, nil
+}
+
+func (s *testConfigSource) set(fn func(c *Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.c)
+}
+
+func TestReloadable(t *testing.T) {
+	ctx := context.Background()
+	src := &testConfigSource{c: *newSQLiteTestConfig("reload_a.db")}
+	src.c.MaxOpenConns = 2
+
+	var mu sync.Mutex
+	var events []ReloadEvent
+	r, err := NewReloadable(ctx, src, WithReloadInterval(0), WithReloadHook(func(e ReloadEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}))
+	require.NoError(t, err)
+	defer r.Close()
+	lastEvent := func() ReloadEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, events)
+		return events[len(events)-1]
+	}
+
+	_, err = r.ExecContext(ctx, "CREATE TABLE who (name TEXT)")
+	require.NoError(t, err)
+	_, err = r.ExecContext(ctx, "INSERT INTO who VALUES ('a')")
+	require.NoError(t, err)
+
+	t.Run("Unchanged", func(t *testing.T) {
+		require.NoError(t, r.Reload(ctx))
+		assert.Empty(t, events)
+	})
+
+	t.Run("Pool", func(t *testing.T) {
+		db := r.DB()
+		src.set(func(c *Config) {
+			c.MaxOpenConns = 5
+			c.MinIdleConns = 1
+			c.ConnMaxIdleTime = time.Minute
+		})
+		require.NoError(t, r.Reload(ctx))
+
+		assert.Same(t, db, r.DB())
+		assert.Equal(t, 5, r.DB().Stats().MaxOpenConnections)
+		assert.Equal(t, 5, r.Config().MaxOpenConns)
+		assert.Same(t, r.Config(), db.Config())
+		assert.NotNil(t, db.idleKeeper)
+		e := lastEvent()
+		assert.Equal(t, ReloadKindPool, e.Kind)
+		assert.NoError(t, e.Err)
+	})
+
+	t.Run("Reconnect failed", func(t *testing.T) {
+		db := r.DB()
+		src.set(func(c *Config) {
+			c.Dialect = "unknown"
+		})
+		require.Error(t, r.Reload(ctx))
+
+		assert.Same(t, db, r.DB())
+		assert.Equal(t, DialectSQLite, r.Config().Dialect)
+		e := lastEvent()
+		assert.Equal(t, ReloadKindReconnect, e.Kind)
+		assert.ErrorIs(t, e.Err, ErrUnsupportedDialect)
+		require.NoError(t, db.PingContext(ctx))
+	})
+
+	t.Run("Reconnect", func(t *testing.T) {
+		old := r.DB()
+		// The transaction delays the drain of the old DB, which does not block the reloads.
+		tx, err := old.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		src.set(func(c *Config) {
+			*c = *newSQLiteTestConfig("reload_b.db")
+		})
+		require.NoError(t, r.Reload(ctx))
+
+		assert.NotSame(t, old, r.DB())
+		assert.Equal(t, "file:reload_b.db", r.DB().DBInfo().Name)
+		src.set(func(c *Config) {
+			c.MaxOpenConns = 3
+		})
+		require.NoError(t, r.Reload(ctx))
+		assert.Equal(t, ReloadKindPool, lastEvent().Kind)
+
+		require.NoError(t, tx.Rollback())
+		require.Eventually(t, func() bool {
+			return lastEvent().Kind == ReloadKindReconnect
+		}, 5*time.Second, 10*time.Millisecond)
+		e := lastEvent()
+		assert.NoError(t, e.Err)
+		assert.NoError(t, e.DrainErr)
+
+		// The old DB is drained and closed.
+		require.Error(t, old.PingContext(ctx))
+		_, err = r.QueryContext(ctx, "SELECT name FROM who")
+		require.Error(t, err)
+	})
+
+	t.Run("Secret rotated", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "password")
+		require.NoError(t, os.WriteFile(path, []byte("a"), 0o600))
+		src.set(func(c *Config) {
+			c.Password = SecretSchemeFile + ":" + path
+		})
+		require.NoError(t, r.Reload(ctx))
+		old := r.DB()
+		require.NoError(t, r.Reload(ctx))
+		assert.Same(t, old, r.DB())
+
+		// The reference is unchanged, but the secret behind it is rotated.
+		require.NoError(t, os.WriteFile(path, []byte("b"), 0o600))
+		require.NoError(t, r.Reload(ctx))
+		assert.NotSame(t, old, r.DB())
+		assert.Equal(t, SecretSchemeFile+":"+path, r.Config().Password)
+	})
+
+	require.NoError(t, r.Close())
+	require.ErrorIs(t, r.Reload(ctx), ErrReloadableClosed)
+}
+
+func TestReloadable_File(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.yaml")
+	write := func(maxOpenConns int) {
+		data := "dialect: sqlite\n" +
+			"name: file:reload_file.db\n" +
+			"params:\n" +
+			"  " + sqliteparams.ConnParams.Cache + ": " + sqlite.CacheShared + "\n" +
+			"  " + sqliteparams.ConnParams.Mode + ": " + sqlite.ModeMemory + "\n" +
+			"max_open_conns: " + strconv.Itoa(maxOpenConns) + "\n"
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	}
+	write(2)
+
+	r, err := NewReloadable(ctx, FileConfigSource(path), WithReloadInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, 2, r.DB().Stats().MaxOpenConnections)
+
+	write(3)
+	assert.Eventually(t, func() bool {
+		return r.DB().Stats().MaxOpenConnections == 3
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestEqualConfig(t *testing.T) {
+	h1 := func(context.Context, *sql.DB) error { return nil }
+	h2 := func(context.Context, *sql.DB) error { return nil }
+	a := &Config{Dialect: DialectSQLite, AfterHandlers: AfterHandlers{h1}, Params: ConfigParams{}}
+	b := &Config{Dialect: DialectSQLite, AfterHandlers: AfterHandlers{h1}}
+	assert.True(t, equalConfig(a, b, false))
+
+	b.MaxOpenConns = 10
+	b.MinIdleConns = 2
+	assert.False(t, equalConfig(a, b, false))
+	assert.True(t, equalConfig(a, b, true))
+
+	b.AfterHandlers = AfterHandlers{h2}
+	assert.False(t, equalConfig(a, b, true))
+
+	b.AfterHandlers = AfterHandlers{h1}
+	b.Hosts = []HostPort{{Host: "h1"}}
+	assert.False(t, equalConfig(a, b, true))
+}
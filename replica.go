@@ -0,0 +1,294 @@
+package hypersql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultReplicaCheckInterval is the interval of replica health checks when it is not set by option.
+const DefaultReplicaCheckInterval = 10 * time.Second
+
+var _ IDB = (*ReplicaSet)(nil)
+
+type (
+	// ReplicaBalancer picks one replica for a read, replicas are all healthy and never empty.
+	ReplicaBalancer interface {
+		Pick(replicas []*Replica) *Replica
+	}
+
+	ReplicaBalancerFunc func(replicas []*Replica) *Replica
+
+	ReplicaSetOption func(*ReplicaSet)
+
+	// Replica is a read replica in ReplicaSet.
+	Replica struct {
+		*DB
+
+		name    string
+		healthy atomic.Bool
+		// lag is the latest sampled lag in nanoseconds, negative means unknown.
+		lag atomic.Int64
+	}
+
+	// ReplicaSet sends writes and transactions to the primary,
+	// and balances QueryContext/QueryRowContext across the healthy replicas.
+	// The replicas lagging over the max lag are skipped if WithMaxReplicaLag is given.
+	// The primary is used for reads when no replica is available.
+	ReplicaSet struct {
+		primary  *DB
+		replicas []*Replica
+
+		balancer      ReplicaBalancer
+		checkInterval time.Duration
+
+		maxLag      time.Duration
+		lagProbe    LagProbe
+		lagObserver LagObserver
+
+		stop      chan struct{}
+		wg        sync.WaitGroup
+		closeOnce sync.Once
+	}
+
+	usePrimaryCtxKey struct{}
+)
+
+func (f ReplicaBalancerFunc) Pick(replicas []*Replica) *Replica {
+	return f(replicas)
+}
+
+// RoundRobinBalancer picks the replicas in turn.
+func RoundRobinBalancer() ReplicaBalancer {
+	var next atomic.Uint64
+	return ReplicaBalancerFunc(func(replicas []*Replica) *Replica {
+		n := next.Add(1) - 1
+		return replicas[n%uint64(len(replicas))]
+	})
+}
+
+// RandomBalancer picks a replica randomly.
+func RandomBalancer() ReplicaBalancer {
+	return ReplicaBalancerFunc(func(replicas []*Replica) *Replica {
+		return replicas[rand.IntN(len(replicas))]
+	})
+}
+
+// LeastConnsBalancer picks the replica with the fewest connections in use.
+func LeastConnsBalancer() ReplicaBalancer {
+	return ReplicaBalancerFunc(func(replicas []*Replica) *Replica {
+		picked, least := replicas[0], replicas[0].Stats().InUse
+		for _, r := range replicas[1:] {
+			if inUse := r.Stats().InUse; inUse < least {
+				picked, least = r, inUse
+			}
+		}
+		return picked
+	})
+}
+
+// WithReplicaBalancer sets the balancer of replicas, RoundRobinBalancer is used by default.
+func WithReplicaBalancer(b ReplicaBalancer) ReplicaSetOption {
+	return func(rs *ReplicaSet) {
+		rs.balancer = b
+	}
+}
+
+// WithReplicaCheckInterval sets the interval of replica health checks.
+func WithReplicaCheckInterval(d time.Duration) ReplicaSetOption {
+	return func(rs *ReplicaSet) {
+		rs.checkInterval = d
+	}
+}
+
+// UsePrimary forces the reads with the returned context to go to the primary.
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, usePrimaryCtxKey{}, true)
+}
+
+func usesPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(usePrimaryCtxKey{}).(bool)
+	return v
+}
+
+// NewReplicaSet opens the primary and the replicas, and starts the replica health checks.
+func NewReplicaSet(ctx context.Context, primary *Config, replicas []*Config, ops ...ReplicaSetOption) (*ReplicaSet, error) {
+	if primary == nil {
+		return nil, ErrNilConfig
+	}
+
+	rs := &ReplicaSet{
+		balancer:      RoundRobinBalancer(),
+		checkInterval: DefaultReplicaCheckInterval,
+		stop:          make(chan struct{}),
+	}
+	for _, o := range ops {
+		o(rs)
+	}
+
+	var err error
+	if rs.primary, err = Open(ctx, primary); err != nil {
+		return nil, fmt.Errorf("unable to open primary: %w", err)
+	}
+	for i, c := range replicas {
+		if c == nil {
+			err = ErrNilConfig
+		} else {
+			var db *DB
+			if db, err = Open(ctx, c); err == nil {
+				r := &Replica{DB: db, name: replicaName(i, c)}
+				r.healthy.Store(true)
+				r.lag.Store(-1)
+				rs.replicas = append(rs.replicas, r)
+				continue
+			}
+		}
+		_ = rs.closeDBs()
+		return nil, fmt.Errorf("unable to open replica %d: %w", i, err)
+	}
+
+	rs.SampleLags(ctx)
+	if len(rs.replicas) > 0 && rs.checkInterval > 0 {
+		rs.wg.Add(1)
+		go rs.checkLoop()
+	}
+	return rs, nil
+}
+
+// Name returns the name of replica, which is host:port if the host is set.
+func (r *Replica) Name() string {
+	return r.name
+}
+
+// Healthy reports whether the replica passed the latest health check.
+func (r *Replica) Healthy() bool {
+	return r.healthy.Load()
+}
+
+func (rs *ReplicaSet) Primary() *DB {
+	return rs.primary
+}
+
+func (rs *ReplicaSet) Replicas() []*Replica {
+	return rs.replicas
+}
+
+func (rs *ReplicaSet) Begin() (*sql.Tx, error) {
+	return rs.primary.Begin()
+}
+
+func (rs *ReplicaSet) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return rs.primary.BeginTx(ctx, opts)
+}
+
+func (rs *ReplicaSet) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return rs.primary.ExecContext(ctx, query, args...)
+}
+
+func (rs *ReplicaSet) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return rs.primary.PrepareContext(ctx, query)
+}
+
+func (rs *ReplicaSet) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return rs.reader(ctx).QueryContext(ctx, query, args...)
+}
+
+func (rs *ReplicaSet) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return rs.reader(ctx).QueryRowContext(ctx, query, args...)
+}
+
+// HealthCheck checks the primary, unhealthy replicas are taken out of rotation instead.
+func (rs *ReplicaSet) HealthCheck(ctx context.Context) error {
+	return rs.primary.HealthCheck(ctx)
+}
+
+// CheckReplicas runs the health checks of replicas and samples their lags immediately.
+func (rs *ReplicaSet) CheckReplicas(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, r := range rs.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			healthy := r.HealthCheck(ctx) == nil
+			r.healthy.Store(healthy)
+			if healthy {
+				rs.sampleLag(ctx, r)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Close stops the health checks and closes the primary and the replicas.
+func (rs *ReplicaSet) Close() error {
+	var err error
+	rs.closeOnce.Do(func() {
+		close(rs.stop)
+		rs.wg.Wait()
+		err = rs.closeDBs()
+	})
+	return err
+}
+
+func (rs *ReplicaSet) reader(ctx context.Context) *sql.DB {
+	if usesPrimary(ctx) {
+		return rs.primary.DB
+	}
+	if r := rs.pick(); r != nil {
+		return r.DB.DB
+	}
+	return rs.primary.DB
+}
+
+func (rs *ReplicaSet) pick() *Replica {
+	healthy := make([]*Replica, 0, len(rs.replicas))
+	for _, r := range rs.replicas {
+		if r.Healthy() && !rs.lagging(r) {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	return rs.balancer.Pick(healthy)
+}
+
+func (rs *ReplicaSet) checkLoop() {
+	defer rs.wg.Done()
+	ticker := time.NewTicker(rs.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rs.stop:
+			return
+		case <-ticker.C:
+			rs.CheckReplicas(context.Background())
+		}
+	}
+}
+
+func (rs *ReplicaSet) closeDBs() error {
+	var errs []error
+	for _, r := range rs.replicas {
+		errs = append(errs, r.Close())
+	}
+	if rs.primary != nil {
+		errs = append(errs, rs.primary.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func replicaName(i int, c *Config) string {
+	if len(c.Host) > 0 {
+		return hostPortToAddr(c.Host, c.Port)
+	}
+	if len(c.Name) > 0 {
+		return c.Name
+	}
+	return fmt.Sprintf("replica-%d", i)
+}
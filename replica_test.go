@@ -0,0 +1,205 @@
+//go:build sqlite
+
+package hypersql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blink-io/hypersql/sqlite"
+	sqliteparams "github.com/blink-io/hypersql/sqlite/params"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func newSQLiteTestConfig(name string) *Config {
+	return &Config{
+		Dialect: DialectSQLite,
+		Name:    "file:" + name,
+		Params: ConfigParams{
+			sqliteparams.ConnParams.Cache: sqlite.CacheShared,
+			sqliteparams.ConnParams.Mode:  sqlite.ModeMemory,
+		},
+	}
+}
+
+// newTestReplicaSet creates a replica set whose members have a table "who" holding their names.
+func newTestReplicaSet(t *testing.T, prefix string, replicas int, ops ...ReplicaSetOption) *ReplicaSet {
+	ctx := context.Background()
+	var rcs []*Config
+	for i := range replicas {
+		rcs = append(rcs, newSQLiteTestConfig(prefix+"_replica"+string(rune('a'+i))+".db"))
+	}
+	rs, err := NewReplicaSet(ctx, newSQLiteTestConfig(prefix+"_primary.db"), rcs, ops...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = rs.Close()
+	})
+
+	dbs := []*DB{rs.Primary()}
+	for _, r := range rs.Replicas() {
+		dbs = append(dbs, r.DB)
+	}
+	for _, db := range dbs {
+		_, err = db.ExecContext(ctx, "CREATE TABLE who (name TEXT)")
+		require.NoError(t, err)
+		_, err = db.ExecContext(ctx, "INSERT INTO who VALUES (?)", db.DBInfo().Name)
+		require.NoError(t, err)
+	}
+	return rs
+}
+
+func queryWho(t *testing.T, ctx context.Context, rs *ReplicaSet) string {
+	var name string
+	require.NoError(t, rs.QueryRowContext(ctx, "SELECT name FROM who").Scan(&name))
+	return name
+}
+
+func TestReplicaSet_RoundRobin(t *testing.T) {
+	ctx := context.Background()
+	rs := newTestReplicaSet(t, "rr", 2, WithReplicaCheckInterval(0))
+
+	got := map[string]int{}
+	for range 4 {
+		got[queryWho(t, ctx, rs)]++
+	}
+	assert.Equal(t, map[string]int{
+		"file:rr_replicaa.db": 2,
+		"file:rr_replicab.db": 2,
+	}, got)
+
+	assert.Equal(t, "file:rr_primary.db", queryWho(t, UsePrimary(ctx), rs))
+
+	res, err := rs.ExecContext(ctx, "INSERT INTO who VALUES ('written')")
+	require.NoError(t, err)
+	n, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	var count int
+	require.NoError(t, rs.Primary().QueryRowContext(ctx, "SELECT count(*) FROM who").Scan(&count))
+	assert.Equal(t, 2, count)
+}
+
+func TestReplicaSet_Balancers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("random", func(t *testing.T) {
+		rs := newTestReplicaSet(t, "random", 2, WithReplicaBalancer(RandomBalancer()))
+		assert.NotEqual(t, "file:random_primary.db", queryWho(t, ctx, rs))
+	})
+
+	t.Run("least conns", func(t *testing.T) {
+		rs := newTestReplicaSet(t, "least", 2, WithReplicaBalancer(LeastConnsBalancer()))
+		conn, err := rs.Replicas()[0].Conn(ctx)
+		require.NoError(t, err)
+		defer conn.Close()
+		for range 3 {
+			assert.Equal(t, "file:least_replicab.db", queryWho(t, ctx, rs))
+		}
+	})
+}
+
+func TestReplicaSet_UnhealthyReplica(t *testing.T) {
+	ctx := context.Background()
+	rs := newTestReplicaSet(t, "unhealthy", 2, WithReplicaCheckInterval(10*time.Millisecond))
+
+	require.NoError(t, rs.Replicas()[0].Close())
+	require.Eventually(t, func() bool {
+		return !rs.Replicas()[0].Healthy()
+	}, time.Second, 10*time.Millisecond)
+
+	for range 3 {
+		assert.Equal(t, "file:unhealthy_replicab.db", queryWho(t, ctx, rs))
+	}
+
+	require.NoError(t, rs.Replicas()[1].Close())
+	rs.CheckReplicas(ctx)
+	assert.Equal(t, "file:unhealthy_primary.db", queryWho(t, ctx, rs))
+}
+
+func TestReplicaSet_MaxLag(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	lags := map[string]time.Duration{
+		"file:lag_replicaa.db": 5 * time.Second,
+		"file:lag_replicab.db": 100 * time.Millisecond,
+	}
+	probe := LagProbeFunc(func(ctx context.Context, db *sql.DB) (time.Duration, error) {
+		var name string
+		if err := db.QueryRowContext(ctx, "SELECT name FROM who").Scan(&name); err != nil {
+			return 0, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return lags[name], nil
+	})
+
+	var failed, observed atomic.Int32
+	rs := newTestReplicaSet(t, "lag", 2,
+		WithReplicaCheckInterval(0),
+		WithLagProbe(probe),
+		WithMaxReplicaLag(time.Second),
+		WithLagObserver(func(replica string, lag time.Duration, err error) {
+			if err != nil {
+				failed.Add(1)
+			} else {
+				observed.Add(1)
+			}
+		}),
+	)
+	// The table is created after the replica set, so the first samples fail.
+	assert.EqualValues(t, 2, failed.Load())
+	assert.Empty(t, rs.ReplicaLags())
+	assert.Equal(t, "file:lag_primary.db", queryWho(t, ctx, rs))
+
+	rs.SampleLags(ctx)
+	assert.EqualValues(t, 2, observed.Load())
+	assert.Equal(t, map[string]time.Duration{
+		"file:lag_replicaa.db": 5 * time.Second,
+		"file:lag_replicab.db": 100 * time.Millisecond,
+	}, rs.ReplicaLags())
+
+	for range 3 {
+		assert.Equal(t, "file:lag_replicab.db", queryWho(t, ctx, rs))
+	}
+
+	mu.Lock()
+	lags["file:lag_replicab.db"] = 2 * time.Second
+	mu.Unlock()
+	rs.CheckReplicas(ctx)
+	assert.Equal(t, "file:lag_primary.db", queryWho(t, ctx, rs))
+
+	reader := sdkmetric.NewManualReader()
+	require.NoError(t, RegisterReplicaLagMetrics(rs, sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	gauge := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Gauge[float64])
+	assert.Len(t, gauge.DataPoints, 2)
+}
+
+func TestSecondsLagProbe(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, newSQLiteTestConfig("probe.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	lag, err := secondsLagProbe("SELECT 1.5").Lag(ctx, db.DB)
+	require.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond, lag)
+
+	_, err = secondsLagProbe("SELECT NULL").Lag(ctx, db.DB)
+	assert.ErrorIs(t, err, ErrReplicationNotRunning)
+
+	lag, err = secondsLagProbe("SELECT 1 WHERE 1 = 0").Lag(ctx, db.DB)
+	require.NoError(t, err)
+	assert.Zero(t, lag)
+}
@@ -0,0 +1,196 @@
+package hypersql
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+const (
+	SecretSchemeEnv    = "env"
+	SecretSchemeFile   = "file"
+	SecretSchemeBase64 = "base64"
+	SecretSchemeExec   = "exec"
+)
+
+var (
+	ErrSecretNotFound = errors.New("secret is not found")
+
+	// ErrSecretNotAllowed means the scheme of reference is not allowed in the field, e.g. exec in a non-secret param.
+	ErrSecretNotAllowed = errors.New("secret scheme is not allowed")
+
+	secretResolversMu sync.RWMutex
+	secretResolvers   = make(map[string]SecretResolver)
+)
+
+// SecretResolver resolves the reference of secret, which is the part after "scheme:".
+// The returned error must not contain the secret.
+type SecretResolver func(ctx context.Context, ref string) (string, error)
+
+// SecretError is the failure of resolving a secret reference, it never contains the reference value.
+type SecretError struct {
+	// Field is the config field of the reference, e.g. Password or Params[sslpassword].
+	Field  string
+	Scheme string
+	Err    error
+}
+
+func (e *SecretError) Error() string {
+	return fmt.Sprintf("unable to resolve %s secret of %s: %v", e.Scheme, e.Field, e.Err)
+}
+
+func (e *SecretError) Unwrap() error {
+	return e.Err
+}
+
+func init() {
+	RegisterSecretResolver(SecretSchemeEnv, resolveEnvSecret)
+	RegisterSecretResolver(SecretSchemeFile, resolveFileSecret)
+	RegisterSecretResolver(SecretSchemeBase64, resolveBase64Secret)
+	RegisterSecretResolver(SecretSchemeExec, resolveExecSecret)
+}
+
+// RegisterSecretResolver registers the resolver of scheme, the existing one is replaced.
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	secretResolversMu.Lock()
+	defer secretResolversMu.Unlock()
+	secretResolvers[scheme] = r
+}
+
+func getSecretResolver(scheme string) SecretResolver {
+	secretResolversMu.RLock()
+	defer secretResolversMu.RUnlock()
+	return secretResolvers[scheme]
+}
+
+// IsSecretRef reports whether the value is a reference of a registered scheme.
+func IsSecretRef(v string) bool {
+	scheme, _, ok := strings.Cut(v, ":")
+	return ok && getSecretResolver(scheme) != nil
+}
+
+// ResolveSecret resolves the value if it is a secret reference, e.g. env:DB_PASSWORD,
+// otherwise the value is returned as is.
+func ResolveSecret(ctx context.Context, v string) (string, error) {
+	return resolveSecret(ctx, "value", v)
+}
+
+func resolveSecret(ctx context.Context, field, v string) (string, error) {
+	scheme, ref, ok := strings.Cut(v, ":")
+	if !ok {
+		return v, nil
+	}
+	r := getSecretResolver(scheme)
+	if r == nil {
+		return v, nil
+	}
+	s, err := r(ctx, ref)
+	if err != nil {
+		return "", &SecretError{Field: field, Scheme: scheme, Err: err}
+	}
+	return s, nil
+}
+
+// ResolveSecrets returns a copy of the config whose secret references are resolved,
+// they are allowed in Password, TLSCert and Params values. The config itself is not modified.
+// The exec scheme is only allowed in Password and the secret params, see IsSecretParam.
+// The file references of TLSCert are kept as paths, so they can be watched.
+// The other references of TLSCert are resolved to the contents, and Watch is turned off for them.
+func (c *Config) ResolveSecrets(ctx context.Context) (*Config, error) {
+	if c == nil {
+		return nil, ErrNilConfig
+	}
+	rc := c.Clone()
+	var errs []error
+	resolve := func(field string, v *string, execAllowed bool) {
+		if scheme, _, ok := strings.Cut(*v, ":"); ok && scheme == SecretSchemeExec && !execAllowed {
+			errs = append(errs, &SecretError{Field: field, Scheme: scheme, Err: ErrSecretNotAllowed})
+			return
+		}
+		s, err := resolveSecret(ctx, field, *v)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		*v = s
+	}
+
+	resolve("Password", &rc.Password, true)
+	if tc := rc.TLSCert; tc != nil {
+		var contents bool
+		resolveFile := func(field string, v *string) {
+			if path, ok := strings.CutPrefix(*v, SecretSchemeFile+":"); ok {
+				*v = path
+				return
+			}
+			contents = contents || IsSecretRef(*v)
+			resolve(field, v, false)
+		}
+		resolveFile("TLSCert.CAFile", &tc.CAFile)
+		resolveFile("TLSCert.CertFile", &tc.CertFile)
+		resolveFile("TLSCert.KeyFile", &tc.KeyFile)
+		if contents {
+			tc.Watch = false
+		}
+	}
+	for k, v := range rc.Params {
+		resolve("Params["+k+"]", &v, IsSecretParam(k))
+		rc.Params[k] = v
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+func resolveEnvSecret(_ context.Context, name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("%w: environment variable %s is not set", ErrSecretNotFound, name)
+	}
+	return v, nil
+}
+
+// resolveFileSecret reads the file, the trailing line break is removed.
+func resolveFileSecret(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: file %s does not exist", ErrSecretNotFound, path)
+		}
+		return "", fmt.Errorf("unable to read file %s: %w", path, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func resolveBase64Secret(_ context.Context, encoded string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// The error of decoding only has the offset of illegal data.
+		return "", err
+	}
+	return string(data), nil
+}
+
+// resolveExecSecret runs the command split by whitespaces and returns its trimmed output.
+// The output of the failed command is not reported, it may contain the secret.
+func resolveExecSecret(ctx context.Context, command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("unable to run command %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
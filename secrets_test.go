@@ -0,0 +1,138 @@
+package hypersql
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSecret(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("HYPERSQL_TEST_SECRET", "from-env")
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+
+	cases := []struct {
+		value    string
+		expected string
+	}{
+		{value: "plain", expected: "plain"},
+		{value: "unknown:value", expected: "unknown:value"},
+		{value: "env:HYPERSQL_TEST_SECRET", expected: "from-env"},
+		{value: "file:" + path, expected: "from-file"},
+		{value: "base64:c2VjcmV0", expected: "secret"},
+		{value: "exec:echo from-exec", expected: "from-exec"},
+	}
+	for _, tc := range cases {
+		v, err := ResolveSecret(ctx, tc.value)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, v)
+	}
+
+	_, err := ResolveSecret(ctx, "env:HYPERSQL_TEST_MISSING")
+	require.ErrorIs(t, err, ErrSecretNotFound)
+	_, err = ResolveSecret(ctx, "file:"+path+".missing")
+	require.ErrorIs(t, err, ErrSecretNotFound)
+	_, err = ResolveSecret(ctx, "exec:false")
+	require.Error(t, err)
+}
+
+func TestResolveSecret_ErrorHidesSecret(t *testing.T) {
+	_, err := ResolveSecret(context.Background(), "base64:not-base64-s3cr3t!")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "s3cr3t")
+
+	var se *SecretError
+	require.True(t, errors.As(err, &se))
+	assert.Equal(t, SecretSchemeBase64, se.Scheme)
+}
+
+func TestRegisterSecretResolver(t *testing.T) {
+	RegisterSecretResolver("vault", func(ctx context.Context, ref string) (string, error) {
+		if ref == "db/password" {
+			return "from-vault", nil
+		}
+		return "", ErrSecretNotFound
+	})
+	t.Cleanup(func() {
+		secretResolversMu.Lock()
+		defer secretResolversMu.Unlock()
+		delete(secretResolvers, "vault")
+	})
+
+	assert.True(t, IsSecretRef("vault:db/password"))
+	assert.False(t, IsSecretRef("other:db/password"))
+
+	v, err := ResolveSecret(context.Background(), "vault:db/password")
+	require.NoError(t, err)
+	assert.Equal(t, "from-vault", v)
+}
+
+func TestConfig_ResolveSecrets(t *testing.T) {
+	t.Setenv("HYPERSQL_TEST_PASSWORD", "secret")
+	c := &Config{
+		Password: "env:HYPERSQL_TEST_PASSWORD",
+		TLSCert:  &TLSCert{CAFile: "base64:Y2E=", KeyFile: "/path/to/key.pem"},
+		Params:   ConfigParams{"sslpassword": "base64:a2V5", "sslmode": "verify-full"},
+	}
+
+	rc, err := c.ResolveSecrets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "secret", rc.Password)
+	assert.Equal(t, "ca", rc.TLSCert.CAFile)
+	assert.Equal(t, "/path/to/key.pem", rc.TLSCert.KeyFile)
+	assert.Equal(t, "key", rc.Params["sslpassword"])
+	assert.Equal(t, "verify-full", rc.Params["sslmode"])
+
+	// The config is not modified.
+	assert.Equal(t, "env:HYPERSQL_TEST_PASSWORD", c.Password)
+	assert.Equal(t, "base64:Y2E=", c.TLSCert.CAFile)
+	assert.Equal(t, "base64:a2V5", c.Params["sslpassword"])
+
+	c.Params["sslkey"] = "env:HYPERSQL_TEST_MISSING"
+	_, err = c.ResolveSecrets(context.Background())
+	require.ErrorIs(t, err, ErrSecretNotFound)
+	assert.Contains(t, err.Error(), "Params[sslkey]")
+}
+
+func TestConfig_ResolveSecrets_TLSCert(t *testing.T) {
+	c := &Config{
+		TLSCert: &TLSCert{CAFile: "file:/path/to/ca.pem", KeyFile: "/path/to/key.pem", Watch: true},
+	}
+	rc, err := c.ResolveSecrets(context.Background())
+	require.NoError(t, err)
+	// The paths are kept, so they are still watched.
+	assert.Equal(t, "/path/to/ca.pem", rc.TLSCert.CAFile)
+	assert.True(t, rc.TLSCert.Watch)
+
+	c.TLSCert.CertFile = "base64:Y2VydA=="
+	rc, err = c.ResolveSecrets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "cert", rc.TLSCert.CertFile)
+	assert.False(t, rc.TLSCert.Watch)
+	assert.True(t, c.TLSCert.Watch)
+}
+
+func TestConfig_ResolveSecrets_Exec(t *testing.T) {
+	c := &Config{
+		Password: "exec:echo secret",
+		Params:   ConfigParams{"sslpassword": "exec:echo key"},
+	}
+	rc, err := c.ResolveSecrets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "secret", rc.Password)
+	assert.Equal(t, "key", rc.Params["sslpassword"])
+
+	for _, c := range []*Config{
+		{Params: ConfigParams{"sslmode": "exec:echo disable"}},
+		{TLSCert: &TLSCert{KeyFile: "exec:cat key.pem"}},
+	} {
+		_, err = c.ResolveSecrets(context.Background())
+		require.ErrorIs(t, err, ErrSecretNotAllowed)
+	}
+}
@@ -0,0 +1,241 @@
+package hypersql
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+	"sync/atomic"
+
+	"github.com/blink-io/hypersql/driver/wrapper"
+)
+
+type (
+	// inflightTracker tracks the in-flight operations of a DB, and rejects new ones after shutdown.
+	// A query is in flight until its rows are closed, a transaction until it is committed or rolled back.
+	inflightTracker struct {
+		mu           sync.Mutex
+		shuttingDown bool
+		next         uint64
+		ops          map[uint64]context.CancelFunc
+		// drained is closed when no operation is in flight after shutdown.
+		drained chan struct{}
+	}
+
+	inflightConn struct {
+		wrapper.Conn
+		t *inflightTracker
+		// inTx is set while a transaction is active, its statements are allowed during shutdown.
+		inTx atomic.Bool
+	}
+
+	inflightStmt struct {
+		wrapper.Stmt
+		c *inflightConn
+	}
+
+	inflightTx struct {
+		driver.Tx
+		c    *inflightConn
+		done func()
+	}
+
+	inflightRows struct {
+		wrapper.Rows
+		done func()
+	}
+)
+
+func newInflightTracker() *inflightTracker {
+	return &inflightTracker{ops: make(map[uint64]context.CancelFunc)}
+}
+
+func (t *inflightTracker) wrap(drv driver.Driver) driver.Driver {
+	return wrapper.WrapDriver(drv, t.open)
+}
+
+// begin registers an operation, which is rejected during shutdown unless it is in a transaction.
+// The returned done must be called when the operation finishes.
+func (t *inflightTracker) begin(ctx context.Context, inTx bool) (context.Context, func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.shuttingDown && !inTx {
+		return ctx, nil, ErrShuttingDown
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	id := t.next
+	t.next++
+	t.ops[id] = cancel
+
+	var once sync.Once
+	done := func() {
+		once.Do(func() {
+			cancel()
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			delete(t.ops, id)
+			if t.shuttingDown && len(t.ops) == 0 {
+				close(t.drained)
+			}
+		})
+	}
+	return ctx, done, nil
+}
+
+func (t *inflightTracker) rejecting() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.shuttingDown
+}
+
+// shutdown rejects new operations and waits for the in-flight ones until ctx is done,
+// then the remaining ones are canceled.
+func (t *inflightTracker) shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	if !t.shuttingDown {
+		t.shuttingDown = true
+		t.drained = make(chan struct{})
+		if len(t.ops) == 0 {
+			close(t.drained)
+		}
+	}
+	drained := t.drained
+	t.mu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	for _, cancel := range t.ops {
+		cancel()
+	}
+	t.mu.Unlock()
+	return ctx.Err()
+}
+
+func (t *inflightTracker) open(open func() (driver.Conn, error)) (driver.Conn, error) {
+	if t.rejecting() {
+		return nil, ErrShuttingDown
+	}
+	conn, err := open()
+	if err != nil {
+		return nil, err
+	}
+	return &inflightConn{Conn: wrapper.Conn{Conn: conn}, t: t}, nil
+}
+
+func (c *inflightConn) begin(ctx context.Context) (context.Context, func(), error) {
+	return c.t.begin(ctx, c.inTx.Load())
+}
+
+func (c *inflightConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *inflightConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	stmt, err := c.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &inflightStmt{Stmt: wrapper.Stmt{Stmt: stmt, Conn: c}, c: c}, nil
+}
+
+func (c *inflightConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *inflightConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	// The transaction is in flight until it ends, its context is canceled only when the shutdown times out.
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := c.Conn.BeginTx(ctx, opts)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	c.inTx.Store(true)
+	return &inflightTx{Tx: tx, c: c, done: done}, nil
+}
+
+func (c *inflightConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return c.Conn.ExecContext(ctx, query, args)
+}
+
+func (c *inflightConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.Conn.QueryContext(ctx, query, args)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return &inflightRows{Rows: wrapper.Rows{Rows: rows}, done: done}, nil
+}
+
+func (c *inflightConn) Ping(ctx context.Context) error {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	return c.Conn.Ping(ctx)
+}
+
+func (s *inflightStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, done, err := s.c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return s.Stmt.ExecContext(ctx, args)
+}
+
+func (s *inflightStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, done, err := s.c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Stmt.QueryContext(ctx, args)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return &inflightRows{Rows: wrapper.Rows{Rows: rows}, done: done}, nil
+}
+
+func (t *inflightTx) Commit() error {
+	defer t.end()
+	return t.Tx.Commit()
+}
+
+func (t *inflightTx) Rollback() error {
+	defer t.end()
+	return t.Tx.Rollback()
+}
+
+func (t *inflightTx) end() {
+	t.c.inTx.Store(false)
+	t.done()
+}
+
+func (r *inflightRows) Close() error {
+	defer r.done()
+	return r.Rows.Close()
+}
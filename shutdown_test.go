@@ -0,0 +1,115 @@
+//go:build sqlite
+
+package hypersql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openShutdownTestDB(t *testing.T, name string) *DB {
+	c := newSQLiteTestConfig(name)
+	c.MaxOpenConns = 2
+	db, err := Open(context.Background(), c)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return db
+}
+
+func shutdownAsync(ctx context.Context, db *DB) chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- db.Shutdown(ctx)
+	}()
+	for !db.inflight.rejecting() {
+		time.Sleep(time.Millisecond)
+	}
+	return done
+}
+
+func TestDB_Shutdown(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Idle", func(t *testing.T) {
+		db := openShutdownTestDB(t, "shutdown_idle.db")
+		require.NoError(t, db.Shutdown(ctx))
+		require.NoError(t, db.Shutdown(ctx))
+		require.Error(t, db.PingContext(ctx))
+	})
+
+	t.Run("Wait for transaction", func(t *testing.T) {
+		db := openShutdownTestDB(t, "shutdown_tx.db")
+		_, err := db.ExecContext(ctx, "CREATE TABLE t (id INTEGER)")
+		require.NoError(t, err)
+
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		done := shutdownAsync(ctx, db)
+
+		_, err = db.ExecContext(ctx, "INSERT INTO t VALUES (1)")
+		require.ErrorIs(t, err, ErrShuttingDown)
+		assert.True(t, IsErrShuttingDown(err))
+		_, err = db.BeginTx(ctx, nil)
+		require.ErrorIs(t, err, ErrShuttingDown)
+
+		// The active transaction is able to finish.
+		_, err = tx.ExecContext(ctx, "INSERT INTO t VALUES (2)")
+		require.NoError(t, err)
+		select {
+		case <-done:
+			t.Fatal("shutdown must wait for the transaction")
+		case <-time.After(20 * time.Millisecond):
+		}
+		require.NoError(t, tx.Commit())
+		require.NoError(t, <-done)
+	})
+
+	t.Run("Wait for rows", func(t *testing.T) {
+		db := openShutdownTestDB(t, "shutdown_rows.db")
+		rows, err := db.QueryContext(ctx, "SELECT 1 UNION ALL SELECT 2")
+		require.NoError(t, err)
+		done := shutdownAsync(ctx, db)
+
+		var n int
+		for rows.Next() {
+			n++
+		}
+		require.NoError(t, rows.Err())
+		assert.Equal(t, 2, n)
+		require.NoError(t, rows.Close())
+		require.NoError(t, <-done)
+	})
+
+	t.Run("Cancel on deadline", func(t *testing.T) {
+		db := openShutdownTestDB(t, "shutdown_cancel.db")
+		queryErr := make(chan error, 1)
+		go func() {
+			var n int64
+			queryErr <- db.QueryRowContext(ctx,
+				"WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c").Scan(&n)
+		}()
+		for db.Stats().InUse == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		sctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		err := db.Shutdown(sctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		select {
+		case err = <-queryErr:
+			require.Error(t, err)
+			assert.False(t, errors.Is(err, ErrShuttingDown))
+		case <-time.After(5 * time.Second):
+			t.Fatal("the in-flight query must be canceled")
+		}
+	})
+}
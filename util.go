@@ -6,6 +6,8 @@ import (
 	"errors"
 	"net"
 	"strconv"
+	"strings"
+	"unicode"
 )
 
 // DoPingContext does invoke ping(context.Context).
@@ -20,3 +22,36 @@ func DoPingContext(ctx context.Context, pinger Pinger) error {
 func hostPortToAddr(host string, port int) string {
 	return net.JoinHostPort(host, strconv.Itoa(port))
 }
+
+// QueryKeyword returns the first keyword of query in upper case, e.g. SELECT.
+// The leading spaces, comments and parentheses are skipped, an empty string is returned
+// when query does not start with a keyword.
+func QueryKeyword(query string) string {
+	for {
+		query = strings.TrimLeftFunc(query, func(r rune) bool {
+			return unicode.IsSpace(r) || r == '('
+		})
+		switch {
+		case strings.HasPrefix(query, "--"):
+			i := strings.IndexByte(query, '\n')
+			if i < 0 {
+				return ""
+			}
+			query = query[i+1:]
+		case strings.HasPrefix(query, "/*"):
+			i := strings.Index(query[2:], "*/")
+			if i < 0 {
+				return ""
+			}
+			query = query[i+4:]
+		default:
+			i := strings.IndexFunc(query, func(r rune) bool {
+				return !unicode.IsLetter(r)
+			})
+			if i >= 0 {
+				query = query[:i]
+			}
+			return strings.ToUpper(query)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package hypersql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryKeyword(t *testing.T) {
+	assert.Equal(t, "SELECT", QueryKeyword("  select 1"))
+	assert.Equal(t, "SELECT", QueryKeyword("(SELECT 1) UNION (SELECT 2)"))
+	assert.Equal(t, "WITH", QueryKeyword("with t as (select 1) select * from t"))
+	assert.Equal(t, "SELECT", QueryKeyword("/* comment */ select 1"))
+	assert.Equal(t, "INSERT", QueryKeyword("-- comment\n\tinsert into t values (1)"))
+	assert.Equal(t, "SELECT", QueryKeyword("SELECT*FROM t"))
+	assert.Empty(t, QueryKeyword("/* unclosed select 1"))
+	assert.Empty(t, QueryKeyword("-- select 1"))
+	assert.Empty(t, QueryKeyword("1"))
+	assert.Empty(t, QueryKeyword(""))
+}